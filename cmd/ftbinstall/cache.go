@@ -0,0 +1,130 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jamiemansfield/mcinstall/util"
+	"github.com/urfave/cli/v2"
+)
+
+var cacheCommand = &cli.Command{
+	Name:  "cache",
+	Usage: "manages the download cache shared between installs",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "stats",
+			Usage: "shows how many files the cache holds, and their size",
+			Action: func(ctx *cli.Context) error {
+				cache, err := getCache(ctx)
+				if err != nil {
+					return err
+				}
+				stats, err := cache.Stats()
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Cache: %s\n", cache.Dir)
+				fmt.Printf("Files: %d\n", stats.Files)
+				fmt.Printf("Size: %s\n", formatSize(stats.Size))
+				if stats.Files > 0 {
+					fmt.Printf("Least recently used: %s\n", stats.Oldest.Format("2006-01-02 15:04"))
+					fmt.Printf("Most recently used: %s\n", stats.Newest.Format("2006-01-02 15:04"))
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "prune",
+			Usage: "removes the least recently used files from the cache",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "maxSize",
+					Usage: "removes files until the cache is no larger than this, for example 10G",
+				},
+				&cli.DurationFlag{
+					Name:  "maxAge",
+					Usage: "removes files that haven't been used for this long, for example 720h",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				if !ctx.IsSet("maxSize") && !ctx.IsSet("maxAge") {
+					return errors.New("usage: ftbinstall cache prune --maxSize size --maxAge duration")
+				}
+				var maxSize int64
+				if ctx.IsSet("maxSize") {
+					var err error
+					maxSize, err = parseSize(ctx.String("maxSize"))
+					if err != nil {
+						return err
+					}
+				}
+
+				cache, err := getCache(ctx)
+				if err != nil {
+					return err
+				}
+				removed, err := cache.Prune(maxSize, ctx.Duration("maxAge"))
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Removed %d file(s), freeing %s\n", removed.Files, formatSize(removed.Size))
+				return nil
+			},
+		},
+	},
+}
+
+// Gets the download cache given by the user.
+func getCache(ctx *cli.Context) (*util.Cache, error) {
+	dir := ctx.String("cache")
+	if dir == "" {
+		var err error
+		dir, err = util.DefaultCacheDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return util.NewCache(dir), nil
+}
+
+var sizeUnits = []string{"B", "K", "M", "G", "T"}
+
+// Parses the given size, such as 512M or 10G.
+func parseSize(size string) (int64, error) {
+	size = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	multiplier := int64(1)
+	for j, unit := range sizeUnits[1:] {
+		if strings.HasSuffix(size, unit) {
+			size = strings.TrimSuffix(size, unit)
+			multiplier = int64(1) << (10 * uint(j+1))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid size " + size + ", expected for example 512M or 10G")
+	}
+	return n * multiplier, nil
+}
+
+func formatSize(size int64) string {
+	value := float64(size)
+	j := 0
+	for value >= 1024 && j < len(sizeUnits)-1 {
+		value /= 1024
+		j++
+	}
+	if j == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %siB", value, sizeUnits[j])
+}
@@ -0,0 +1,64 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jamiemansfield/mcinstall/ftb"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// The exit code used by check when an update is available, any errors
+	// exit with 1.
+	updateAvailableExitCode = 100
+)
+
+var checkCommand = &cli.Command{
+	Name:      "check",
+	Usage:     "checks whether an update is available for an installed pack, exiting with 100 if so",
+	ArgsUsage: "[directory]",
+	Flags: []cli.Flag{
+		formatFlag,
+		&cli.StringFlag{
+			Name:  "channel",
+			Usage: "the versions to consider, either latest, latest-release or latest-beta",
+			Value: ftb.LatestVersion,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		check, err := newInstaller(ctx).CheckForUpdate(ctx.Context, newClient(ctx), ctx.Args().First(), ctx.String("channel"))
+		if err != nil {
+			return err
+		}
+
+		if ctx.String("format") == "json" {
+			if err := printJson(check); err != nil {
+				return err
+			}
+		} else {
+			current := "an unlisted version"
+			if check.Current != nil {
+				current = check.Current.Name
+			}
+
+			if !check.UpdateAvailable {
+				fmt.Printf("%s is up to date, at %s\n", check.Pack.Name, current)
+			} else {
+				fmt.Printf("An update is available for %s, from %s to %s\n", check.Pack.Name, current, check.Latest.Name)
+				if check.Changelog != "" {
+					fmt.Println()
+					fmt.Println(check.Changelog)
+				}
+			}
+		}
+
+		if check.UpdateAvailable {
+			return cli.Exit("", updateAvailableExitCode)
+		}
+		return nil
+	},
+}
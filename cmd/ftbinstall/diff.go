@@ -0,0 +1,123 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/ftb"
+	"github.com/urfave/cli/v2"
+)
+
+var diffCommand = &cli.Command{
+	Name:      "diff",
+	Usage:     "shows what changes between two versions of a pack",
+	ArgsUsage: "pack fromVersion toVersion | --installed directory toVersion",
+	Flags: []cli.Flag{
+		formatFlag,
+		&cli.StringFlag{
+			Name:  "installed",
+			Usage: "compares the pack installed in the given directory, rather than fromVersion",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		client := newClient(ctx)
+
+		var diff *ftb.VersionDiff
+		if ctx.IsSet("installed") {
+			if ctx.Args().Len() < 1 {
+				return errors.New("usage: ftbinstall diff --installed directory toVersion")
+			}
+
+			ftbInstaller := newInstaller(ctx)
+			settings, err := ftbInstaller.GetInstallSettings(ctx.String("installed"))
+			if err != nil {
+				return err
+			}
+			to, err := getVersion(client, settings.Pack, ctx.Args().Get(0))
+			if err != nil {
+				return err
+			}
+			diff, err = ftbInstaller.DiffInstall(ctx.String("installed"), to)
+			if err != nil {
+				return err
+			}
+		} else {
+			if ctx.Args().Len() < 3 {
+				return errors.New("usage: ftbinstall diff pack fromVersion toVersion")
+			}
+
+			packId, err := strconv.Atoi(ctx.Args().Get(0))
+			if err != nil {
+				return errors.New("usage: pack must be an integer")
+			}
+			from, err := getVersion(client, packId, ctx.Args().Get(1))
+			if err != nil {
+				return err
+			}
+			to, err := getVersion(client, packId, ctx.Args().Get(2))
+			if err != nil {
+				return err
+			}
+			diff = ftb.DiffVersions(from, to)
+		}
+
+		if ctx.String("format") == "json" {
+			return printJson(diff)
+		}
+		printDiff(diff)
+		return nil
+	},
+}
+
+// Gets the version of the given pack, that the query refers to.
+func getVersion(client *modpacksch.Client, packId int, query string) (*modpacksch.PackVersion, error) {
+	pack, err := client.Packs.GetPack(packId)
+	if err != nil {
+		return nil, err
+	}
+	versionInfo, err := ftb.ResolveVersion(pack, query)
+	if err != nil {
+		return nil, err
+	}
+	return client.Packs.GetVersion(packId, versionInfo.ID)
+}
+
+func printDiff(diff *ftb.VersionDiff) {
+	if len(diff.Targets) == 0 && len(diff.Files) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+
+	for _, target := range diff.Targets {
+		if target.FromVersion == "" {
+			fmt.Printf("%s %s has been added\n", target.Name, target.ToVersion)
+		} else if target.ToVersion == "" {
+			fmt.Printf("%s %s has been removed\n", target.Name, target.FromVersion)
+		} else {
+			fmt.Printf("%s changes from %s to %s\n", target.Name, target.FromVersion, target.ToVersion)
+		}
+	}
+
+	printFileDiffs("Files added", diff.FilesWith(ftb.FileAdded))
+	printFileDiffs("Files removed", diff.FilesWith(ftb.FileRemoved))
+	printFileDiffs("Files changed", diff.FilesWith(ftb.FileChanged))
+	printFileDiffs("Configs that will be touched", diff.Configs())
+}
+
+func printFileDiffs(title string, files []*ftb.FileDiff) {
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s (%d):\n", title, len(files))
+	for _, file := range files {
+		fmt.Printf("\t%s\n", file.Path)
+	}
+}
@@ -0,0 +1,50 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/jamiemansfield/mcinstall/ftb"
+	"github.com/urfave/cli/v2"
+)
+
+var exportCommand = &cli.Command{
+	Name:      "export",
+	Usage:     "exports everything needed to install a pack version to an offline bundle",
+	ArgsUsage: "pack version file",
+	Action: func(ctx *cli.Context) error {
+		if ctx.Args().Len() < 3 {
+			return errors.New("usage: ftbinstall export pack version file")
+		}
+		packId, err := strconv.Atoi(ctx.Args().Get(0))
+		if err != nil {
+			return errors.New("usage: pack must be an integer")
+		}
+
+		client := newClient(ctx)
+		pack, err := client.Packs.GetPack(packId)
+		if err != nil {
+			return err
+		}
+		versionInfo, err := ftb.ResolveVersion(pack, ctx.Args().Get(1))
+		if err != nil {
+			return err
+		}
+		version, err := client.Packs.GetVersion(packId, versionInfo.ID)
+		if err != nil {
+			return err
+		}
+
+		path := ctx.Args().Get(2)
+		if err := newInstaller(ctx).ExportBundleContext(ctx.Context, path, pack, version); err != nil {
+			return err
+		}
+		fmt.Printf("Exported %s v%s to %s\n", pack.Name, version.Name, path)
+		return nil
+	},
+}
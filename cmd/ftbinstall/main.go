@@ -5,10 +5,12 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
 	"time"
 
@@ -24,6 +26,18 @@ func main() {
 		Name:    "ftbinstall",
 		Usage:   "install packs from the modpacks.ch service",
 		Version: "0.1.0-indev",
+		Commands: []*cli.Command{
+			uninstallCommand,
+			verifyCommand,
+			repairCommand,
+			searchCommand,
+			infoCommand,
+			versionsCommand,
+			diffCommand,
+			checkCommand,
+			cacheCommand,
+			exportCommand,
+		},
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "target",
@@ -37,22 +51,61 @@ func main() {
 				Usage:   "the user-agent used for eequests",
 				Value:   util.UserAgent,
 			},
+			&cli.IntFlag{
+				Name:  "maxFailures",
+				Usage: "the number of files allowed to fail to install, -1 for no limit",
+				Value: -1,
+			},
+			&cli.StringSliceFlag{
+				Name:  "protect",
+				Usage: "a gitignore-style pattern of files that updates shouldn't touch, can be repeated",
+			},
+			&cli.StringFlag{
+				Name:  "cache",
+				Usage: "the download cache shared between installs, defaults to the user's cache directory",
+			},
+			&cli.BoolFlag{
+				Name:  "noCache",
+				Usage: "downloads every file, without using the download cache",
+			},
+			&cli.StringFlag{
+				Name:  "mirrors",
+				Usage: "a file of rules rewriting URL prefixes to mirrors, tried in order",
+			},
+			&cli.StringFlag{
+				Name:  "bundle",
+				Usage: "installs from the given offline bundle, created using export, rather than modpacks.ch",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "prints what the install would do, without making any changes",
+			},
 		},
-		Action: func(ctx *cli.Context) error {
-			if ctx.Args().Len() < 2 {
-				return errors.New("usage: ftbinstall pack version")
+		Before: func(ctx *cli.Context) error {
+			if ctx.IsSet("mirrors") {
+				rules, err := util.ReadMirrorRules(ctx.String("mirrors"))
+				if err != nil {
+					return err
+				}
+				util.UseMirrors(rules)
 			}
 
-			packId, err := strconv.Atoi(ctx.Args().Get(0))
-			if err != nil {
-				return errors.New("usage: pack must be an integer")
+			if ctx.Bool("noCache") {
+				return nil
 			}
-			versionId, err := strconv.Atoi(ctx.Args().Get(1))
+			cache, err := getCache(ctx)
 			if err != nil {
-				return errors.New("usage: version must be an integer")
+				return err
 			}
+			util.DownloadCache = cache
+			return nil
+		},
+		Action: func(ctx *cli.Context) error {
+			if ctx.Args().Len() < 2 && !ctx.IsSet("bundle") {
+				return errors.New("usage: ftbinstall pack version | --bundle file")
+			}
+
 			installTargetRaw := ctx.Value("target").(string)
-			userAgent := ctx.Value("userAgent").(string)
 
 			var installTarget minecraft.InstallTarget
 			if installTargetRaw == "client" || installTargetRaw == "c" {
@@ -63,23 +116,60 @@ func main() {
 				return errors.New("unknown install target " + installTargetRaw)
 			}
 
-			client := modpacksch.NewClient(nil)
-			client.UserAgent = userAgent
+			// Install from an offline bundle, without network access
+			var bundle *ftb.Bundle
+			var pack *modpacksch.Pack
+			var version *modpacksch.PackVersion
+			if ctx.IsSet("bundle") {
+				var err error
+				bundle, err = ftb.OpenBundle(ctx.String("bundle"))
+				if err != nil {
+					return err
+				}
+				defer bundle.Close()
+				pack = bundle.Pack
+				version = bundle.Version
+			} else {
+				packId, err := strconv.Atoi(ctx.Args().Get(0))
+				if err != nil {
+					return errors.New("usage: pack must be an integer")
+				}
 
-			pack, err := client.Packs.GetPack(packId)
-			if err != nil {
-				return err
+				client := newClient(ctx)
+
+				pack, err = client.Packs.GetPack(packId)
+				if err != nil {
+					return err
+				}
+
+				versionInfo, err := ftb.ResolveVersion(pack, ctx.Args().Get(1))
+				if err != nil {
+					return err
+				}
+				version, err = client.Packs.GetVersion(packId, versionInfo.ID)
+				if err != nil {
+					return err
+				}
 			}
 
-			version, err := client.Packs.GetVersion(packId, versionId)
-			if err != nil {
-				return err
+			ftbInstaller := newInstaller(ctx)
+
+			if ctx.Bool("dry-run") {
+				plan, err := ftbInstaller.PlanPackVersion(installTarget, "", pack, version)
+				if err != nil {
+					return err
+				}
+				printPlan(plan)
+				return nil
 			}
 
 			start := time.Now()
-
-			ftbInstaller := ftb.NewInstaller(10)
-			result := ftbInstaller.InstallPackVersion(installTarget, "", pack, version)
+			var result error
+			if bundle != nil {
+				result = ftbInstaller.InstallBundleContext(ctx.Context, installTarget, "", bundle)
+			} else {
+				result = ftbInstaller.InstallPackVersionContext(ctx.Context, installTarget, "", pack, version)
+			}
 
 			elapsed := time.Since(start)
 			fmt.Printf("Installation took %s", elapsed)
@@ -88,8 +178,39 @@ func main() {
 		},
 	}
 
-	err := app.Run(os.Args)
+	// Cancel the install on interrupt, so we don't leave the destination
+	// in an inconsistent state
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		fmt.Println("Interrupted, cancelling install...")
+		cancel()
+
+		// A second interrupt terminates immediately
+		signal.Stop(interrupt)
+	}()
+
+	err := app.RunContext(ctx, os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// Creates a modpacks.ch client, using the user-agent given by the user.
+func newClient(ctx *cli.Context) *modpacksch.Client {
+	client := modpacksch.NewClient(util.HTTPClient)
+	client.UserAgent = ctx.Value("userAgent").(string)
+	return client
+}
+
+// Creates an installer, using the failure policy and protection rules given
+// by the user.
+func newInstaller(ctx *cli.Context) *ftb.Installer {
+	installer := ftb.NewInstaller(10)
+	installer.FailurePolicy = ftb.TolerateFailures(ctx.Int("maxFailures"))
+	installer.Protect = ctx.StringSlice("protect")
+	return installer
+}
@@ -0,0 +1,82 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jamiemansfield/mcinstall/ftb"
+)
+
+// Prints the given install plan, for the user to review.
+func printPlan(plan *ftb.Plan) {
+	fmt.Printf("Dry run: installing %s v%s to %s\n", plan.Pack.Name, plan.Version.Name, plan.Destination)
+	if plan.Fresh {
+		fmt.Println("No existing installation detected, this will be a fresh install")
+	} else {
+		fmt.Println("Existing installation of " + strconv.Itoa(plan.Settings.Pack) + " v" + strconv.Itoa(plan.Settings.Version) + " will be updated")
+	}
+
+	fmt.Println()
+	fmt.Println("Minecraft " + plan.GameVersion.String())
+	for _, modLoader := range plan.ModLoaders {
+		fmt.Printf("Modloader: %s %s\n", modLoader.Name, modLoader.Version)
+	}
+
+	printPlannedFiles("Files to download", plan.FilesFor(ftb.DownloadFile))
+	printPlannedFiles("Files skipped, as their sha1 matches", plan.FilesFor(ftb.SkipFile))
+	printPlannedFiles("Files to merge, as they have been modified", plan.FilesFor(ftb.MergeFile))
+	printPlannedFiles("Files diverted, as they have been modified", plan.FilesFor(ftb.DivertFile))
+	printPlannedFiles("Files to delete, as they are no longer in the pack", plan.FilesFor(ftb.DeleteFile))
+	printPlannedFiles("Files kept, as they have been modified", plan.FilesFor(ftb.KeepFile))
+	printPlannedFiles("Files protected by rules", plan.FilesFor(ftb.ProtectFile))
+
+	if plan.Profile != nil {
+		fmt.Println()
+		fmt.Println("Launcher profile:")
+		fmt.Printf("\t%s (%s)\n", plan.Profile.Name, plan.Profile.Version)
+		if plan.Profile.JavaArgs != "" {
+			fmt.Printf("\tJava arguments: %s\n", plan.Profile.JavaArgs)
+		}
+	}
+	if len(plan.LaunchFiles) > 0 {
+		fmt.Println()
+		fmt.Println("Server launch configuration:")
+		for _, file := range plan.LaunchFiles {
+			if file.Keep {
+				fmt.Printf("\t%s, kept as it has been modified\n", file.Name)
+			} else {
+				fmt.Printf("\t%s\n", file.Name)
+			}
+		}
+	}
+
+	if plan.Memory != nil {
+		fmt.Println()
+		fmt.Printf("Memory: %s\n", plan.Memory)
+		if plan.Memory.LowMemory() {
+			fmt.Printf("Warning: this machine only has %dM of memory\n", plan.Memory.Available)
+		}
+	}
+}
+
+func printPlannedFiles(title string, files []*ftb.PlannedFile) {
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%s (%d):\n", title, len(files))
+	for _, file := range files {
+		if file.Action == ftb.DivertFile {
+			fmt.Printf("\t%s -> %s\n", file.Path, file.Dest)
+		} else if file.Action == ftb.ProtectFile {
+			fmt.Printf("\t%s, by %s\n", file.Path, file.Rule)
+		} else {
+			fmt.Printf("\t%s\n", file.Path)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/ftb"
+	"github.com/urfave/cli/v2"
+)
+
+var formatFlag = &cli.StringFlag{
+	Name:  "format",
+	Usage: "the output format, either table or json",
+	Value: "table",
+}
+
+var searchCommand = &cli.Command{
+	Name:      "search",
+	Usage:     "searches modpacks.ch for packs",
+	ArgsUsage: "query",
+	Flags: []cli.Flag{
+		formatFlag,
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: "the maximum number of packs to show",
+			Value: 10,
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		if ctx.Args().Len() < 1 {
+			return errors.New("usage: ftbinstall search query")
+		}
+
+		ids, err := ftb.SearchPacks(ctx.Context, nil, strings.Join(ctx.Args().Slice(), " "), ctx.Int("limit"))
+		if err != nil {
+			return err
+		}
+
+		client := newClient(ctx)
+		var packs []*packSummary
+		for _, id := range ids {
+			pack, err := client.Packs.GetPack(id)
+			if err != nil {
+				return err
+			}
+			packs = append(packs, summarisePack(pack, false))
+		}
+
+		if ctx.String("format") == "json" {
+			return printJson(packs)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tAUTHORS\tMINECRAFT\tMODLOADERS\tTAGS")
+		for _, pack := range packs {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", pack.ID, pack.Name,
+				strings.Join(pack.Authors, ", "), strings.Join(pack.GameVersions, ", "),
+				strings.Join(pack.ModLoaders, ", "), strings.Join(pack.Tags, ", "))
+		}
+		return w.Flush()
+	},
+}
+
+var infoCommand = &cli.Command{
+	Name:      "info",
+	Usage:     "shows the details of a pack",
+	ArgsUsage: "pack",
+	Flags: []cli.Flag{
+		formatFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		pack, err := getPack(ctx)
+		if err != nil {
+			return err
+		}
+		summary := summarisePack(pack, true)
+
+		if ctx.String("format") == "json" {
+			return printJson(summary)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(w, "ID:\t%d\n", summary.ID)
+		fmt.Fprintf(w, "Name:\t%s\n", summary.Name)
+		fmt.Fprintf(w, "Synopsis:\t%s\n", summary.Synopsis)
+		fmt.Fprintf(w, "Authors:\t%s\n", strings.Join(summary.Authors, ", "))
+		fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(summary.Tags, ", "))
+		fmt.Fprintf(w, "Minecraft:\t%s\n", strings.Join(summary.GameVersions, ", "))
+		fmt.Fprintf(w, "Modloaders:\t%s\n", strings.Join(summary.ModLoaders, ", "))
+		fmt.Fprintf(w, "Installs:\t%d\n", summary.Installs)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+		return printVersions(summary.Versions)
+	},
+}
+
+var versionsCommand = &cli.Command{
+	Name:      "versions",
+	Usage:     "shows the version history of a pack",
+	ArgsUsage: "pack",
+	Flags: []cli.Flag{
+		formatFlag,
+	},
+	Action: func(ctx *cli.Context) error {
+		pack, err := getPack(ctx)
+		if err != nil {
+			return err
+		}
+		summary := summarisePack(pack, true)
+
+		if ctx.String("format") == "json" {
+			return printJson(summary.Versions)
+		}
+		return printVersions(summary.Versions)
+	},
+}
+
+type packSummary struct {
+	ID           int               `json:"id"`
+	Name         string            `json:"name"`
+	Synopsis     string            `json:"synopsis"`
+	Authors      []string          `json:"authors"`
+	Tags         []string          `json:"tags"`
+	GameVersions []string          `json:"gameVersions"`
+	ModLoaders   []string          `json:"modLoaders"`
+	Installs     int               `json:"installs"`
+	Versions     []*versionSummary `json:"versions,omitempty"`
+}
+
+type versionSummary struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	GameVersion string    `json:"gameVersion"`
+	ModLoaders  []string  `json:"modLoaders"`
+	Updated     time.Time `json:"updated"`
+}
+
+// Gets the pack given as the first argument.
+func getPack(ctx *cli.Context) (*modpacksch.Pack, error) {
+	packId, err := strconv.Atoi(ctx.Args().First())
+	if err != nil {
+		return nil, errors.New("usage: pack must be an integer")
+	}
+	return newClient(ctx).Packs.GetPack(packId)
+}
+
+// Summarises the given pack, for display. The Minecraft versions and
+// modloaders are gathered from every version of the pack.
+func summarisePack(pack *modpacksch.Pack, includeVersions bool) *packSummary {
+	summary := &packSummary{
+		ID:           pack.ID,
+		Name:         pack.Name,
+		Synopsis:     pack.Synopsis,
+		Authors:      []string{},
+		Tags:         []string{},
+		GameVersions: []string{},
+		ModLoaders:   []string{},
+		Installs:     pack.Installs,
+	}
+	for _, author := range pack.Authors {
+		summary.Authors = append(summary.Authors, author.Name)
+	}
+	for _, tag := range pack.Tags {
+		summary.Tags = append(summary.Tags, tag.Name)
+	}
+
+	// Newest versions first
+	versions := append([]*modpacksch.VersionInfo{}, pack.Versions...)
+	sort.Slice(versions, func(a, b int) bool {
+		return versions[a].ID > versions[b].ID
+	})
+	for _, version := range versions {
+		versionSummary := &versionSummary{
+			ID:         version.ID,
+			Name:       version.Name,
+			Type:       version.Type,
+			ModLoaders: []string{},
+			Updated:    time.Unix(version.Updated, 0),
+		}
+		for _, target := range version.Targets {
+			if target.Type == "game" {
+				versionSummary.GameVersion = target.Version
+				summary.GameVersions = appendUnique(summary.GameVersions, target.Version)
+			} else if target.Type == "modloader" {
+				versionSummary.ModLoaders = append(versionSummary.ModLoaders, target.Name+" "+target.Version)
+				summary.ModLoaders = appendUnique(summary.ModLoaders, target.Name)
+			}
+		}
+		if includeVersions {
+			summary.Versions = append(summary.Versions, versionSummary)
+		}
+	}
+
+	return summary
+}
+
+func printVersions(versions []*versionSummary) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tMINECRAFT\tMODLOADERS\tUPDATED")
+	for _, version := range versions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", version.ID, version.Name, version.Type,
+			version.GameVersion, strings.Join(version.ModLoaders, ", "), version.Updated.Format("2006-01-02"))
+	}
+	return w.Flush()
+}
+
+func printJson(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(v)
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,58 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jamiemansfield/mcinstall/ftb"
+	"github.com/urfave/cli/v2"
+)
+
+var uninstallCommand = &cli.Command{
+	Name:      "uninstall",
+	Usage:     "removes an installed pack, keeping modified files",
+	ArgsUsage: "[directory]",
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "removeModLoader",
+			Usage: "removes the modloader version from the launcher, if no other profile uses it",
+		},
+	},
+	Action: func(ctx *cli.Context) error {
+		dest := ctx.Args().First()
+
+		ftbInstaller := newInstaller(ctx)
+		result, err := ftbInstaller.Uninstall(dest, &ftb.UninstallOptions{
+			RemoveModLoader: ctx.Bool("removeModLoader"),
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Removed %d file(s)\n", len(result.Removed))
+		if result.ProfileRemoved {
+			fmt.Println("Removed launcher profile")
+		}
+		if result.RemovedVersion != "" {
+			fmt.Printf("Removed launcher version %s\n", result.RemovedVersion)
+		} else if result.VersionKeptReason != "" {
+			fmt.Printf("Kept launcher version, as %s\n", result.VersionKeptReason)
+		}
+
+		if len(result.Kept) > 0 {
+			fmt.Println()
+			fmt.Printf("Kept %d file(s):\n", len(result.Kept))
+			for _, file := range result.Kept {
+				fmt.Printf("\t%s (%s)\n", file.Path, file.Reason)
+			}
+		}
+
+		fmt.Println()
+		fmt.Println("Anything not installed by the pack (such as saves), and any files")
+		fmt.Printf("diverted to %s by previous updates, have been left in place.\n", ftbInstaller.DataDir)
+		return nil
+	},
+}
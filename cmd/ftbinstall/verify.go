@@ -0,0 +1,79 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jamiemansfield/mcinstall/ftb"
+	"github.com/urfave/cli/v2"
+)
+
+var verifyCommand = &cli.Command{
+	Name:      "verify",
+	Usage:     "checks an installed pack for missing, corrupted or modified files",
+	ArgsUsage: "[directory]",
+	Action: func(ctx *cli.Context) error {
+		report, err := newInstaller(ctx).Verify(ctx.Args().First())
+		if err != nil {
+			return err
+		}
+
+		printVerifyReport(report)
+
+		if len(report.FilesWith(ftb.FileMissing)) > 0 || len(report.FilesWith(ftb.FileCorrupted)) > 0 {
+			return cli.Exit("Some files are missing or corrupted, run 'ftbinstall repair' to fix them", 1)
+		}
+		return nil
+	},
+}
+
+var repairCommand = &cli.Command{
+	Name:      "repair",
+	Usage:     "re-downloads any missing or corrupted files of an installed pack",
+	ArgsUsage: "[directory]",
+	Action: func(ctx *cli.Context) error {
+		dest := ctx.Args().First()
+		ftbInstaller := newInstaller(ctx)
+
+		settings, err := ftbInstaller.GetInstallSettings(dest)
+		if err != nil {
+			return err
+		}
+		version, err := newClient(ctx).Packs.GetVersion(settings.Pack, settings.Version)
+		if err != nil {
+			return err
+		}
+
+		result, err := ftbInstaller.Repair(ctx.Context, dest, version)
+		if result != nil {
+			printVerifyReport(result.Report)
+			fmt.Println()
+			fmt.Printf("Repaired %d file(s)\n", len(result.Repaired))
+			for _, path := range result.Unrepairable {
+				fmt.Printf("\t%s can't be repaired, as it is no longer a part of the pack\n", path)
+			}
+			for _, path := range result.Protected {
+				fmt.Printf("\t%s wasn't repaired, as it is protected\n", path)
+			}
+		}
+		return err
+	},
+}
+
+func printVerifyReport(report *ftb.VerifyReport) {
+	fmt.Printf("%d file(s) ok\n", len(report.FilesWith(ftb.FileOK)))
+	for _, status := range []ftb.FileStatus{ftb.FileMissing, ftb.FileCorrupted, ftb.FileModified, ftb.FileOutsideInstall} {
+		files := report.FilesWith(status)
+		if len(files) == 0 {
+			continue
+		}
+
+		fmt.Printf("%d file(s) %s:\n", len(files), status)
+		for _, file := range files {
+			fmt.Printf("\t%s\n", file.Path)
+		}
+	}
+}
@@ -20,6 +20,43 @@ func main() {
 		Name:    "technicinstall",
 		Usage:   "install packs from the Technic Pack",
 		Version: "0.1.0-indev",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "cache",
+				Usage: "the download cache shared between installs, defaults to the user's cache directory",
+			},
+			&cli.BoolFlag{
+				Name:  "noCache",
+				Usage: "downloads every file, without using the download cache",
+			},
+			&cli.StringFlag{
+				Name:  "mirrors",
+				Usage: "a file of rules rewriting URL prefixes to mirrors, tried in order",
+			},
+		},
+		Before: func(ctx *cli.Context) error {
+			if ctx.IsSet("mirrors") {
+				rules, err := util.ReadMirrorRules(ctx.String("mirrors"))
+				if err != nil {
+					return err
+				}
+				util.UseMirrors(rules)
+			}
+
+			if ctx.Bool("noCache") {
+				return nil
+			}
+			dir := ctx.String("cache")
+			if dir == "" {
+				var err error
+				dir, err = util.DefaultCacheDir()
+				if err != nil {
+					return err
+				}
+			}
+			util.DownloadCache = util.NewCache(dir)
+			return nil
+		},
 		Action: func(ctx *cli.Context) error {
 			if ctx.Args().Len() < 2 {
 				return errors.New("usage: technicinstall pack version")
@@ -27,7 +64,7 @@ func main() {
 			packSlug := ctx.Args().Get(0)
 			version := ctx.Args().Get(1)
 
-			client := platform.NewClient(nil)
+			client := platform.NewClient(util.HTTPClient)
 			client.UserAgent = util.UserAgent
 			client.Build = "mcinstall"
 
@@ -0,0 +1,257 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fabric
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/minecraft/manifest"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	defaultMetaRoot = "https://meta.fabricmc.net/"
+
+	// The jar that servers are launched with
+	ServerLaunchJar = "fabric-server-launch.jar"
+
+	// The vanilla server jar, as expected by the server launcher
+	serverJar = "server.jar"
+)
+
+var (
+	ErrVersionNotFound = errors.New("fabric: version not found")
+)
+
+// Loader describes a modloader that is installed in the same way as Fabric
+// Loader - with the same meta API, and server launcher. Forks of Fabric
+// Loader, such as Quilt Loader, are installed this way.
+type Loader struct {
+	// The name of the loader, for example Fabric Loader
+	Name string
+
+	// The Maven group and artifact of the loader, where the artifact is
+	// also used for the names of launcher versions.
+	Group    string
+	Artifact string
+
+	// The version of the meta API to use, for example v2
+	MetaVersion string
+
+	// The jar that servers are launched with
+	ServerLaunchJar string
+
+	// The main class of the server launcher, should the meta API not
+	// give one.
+	LauncherMainClass string
+}
+
+// FabricLoader is Fabric Loader itself.
+var FabricLoader = &Loader{
+	Name:              "Fabric Loader",
+	Group:             "net.fabricmc",
+	Artifact:          "fabric-loader",
+	MetaVersion:       "v2",
+	ServerLaunchJar:   ServerLaunchJar,
+	LauncherMainClass: "net.fabricmc.loader.launch.server.FabricServerLauncher",
+}
+
+// VersionName gets the name of the launcher version for the given version
+// of the loader, such as fabric-loader-0.14.9-1.19.2.
+func (l *Loader) VersionName(mcVersion *minecraft.Version, loaderVersion string) string {
+	return l.Artifact + "-" + loaderVersion + "-" + mcVersion.String()
+}
+
+type Installer struct {
+	// The URL to the loader's meta API, or a mirror.
+	MetaRoot *url.URL
+
+	// The loader to install, Fabric Loader should this be nil.
+	Loader *Loader
+}
+
+// NewInstaller returns a new Installer to use for installing Fabric
+// Loader.
+func NewInstaller() *Installer {
+	metaRoot, _ := url.Parse(defaultMetaRoot)
+
+	return &Installer{
+		MetaRoot: metaRoot,
+	}
+}
+
+// VersionName gets the name of the launcher version for the given version
+// of Fabric Loader, such as fabric-loader-0.14.9-1.19.2.
+func VersionName(mcVersion *minecraft.Version, loaderVersion string) string {
+	return FabricLoader.VersionName(mcVersion, loaderVersion)
+}
+
+// Gets the loader to install.
+func (i *Installer) loader() *Loader {
+	if i.Loader == nil {
+		return FabricLoader
+	}
+	return i.Loader
+}
+
+// Installs Fabric Loader (or the installer's Loader) to the given
+// destination, for the given target.
+// If the target is Server, the destination will be the root directory of
+// the server; if the target is Client, the destination will be the
+// launcher's root directory.
+func (i *Installer) InstallFabric(target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, loaderVersion string) error {
+	return i.InstallFabricContext(context.Background(), target, dest, mcVersion, loaderVersion)
+}
+
+// See InstallFabric
+// Should the context be cancelled, any in-progress download will be
+// aborted.
+func (i *Installer) InstallFabricContext(ctx context.Context, target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, loaderVersion string) error {
+	loader := i.loader()
+	versionName := loader.VersionName(mcVersion, loaderVersion)
+	loaderPath, err := util.MavenPath(loader.Group + ":" + loader.Artifact + ":" + loaderVersion)
+	if err != nil {
+		return err
+	}
+
+	// Check whether we need to install the loader
+	_, serverCheck := os.Stat(filepath.Join(dest, loader.ServerLaunchJar))
+	_, loaderCheck := os.Stat(filepath.Join(dest, "libraries", filepath.FromSlash(loaderPath)))
+	_, clientCheck := os.Stat(filepath.Join(dest, "versions", versionName, versionName+".json"))
+	if (serverCheck == nil && loaderCheck == nil && target == minecraft.Server) ||
+		(clientCheck == nil && target == minecraft.Client) {
+		fmt.Println(loader.Name + " install found, skipping...")
+		return nil
+	}
+	fmt.Printf("Installing %s %s for Minecraft %s...\n", loader.Name, loaderVersion, mcVersion)
+
+	profile, err := i.GetProfile(ctx, target, mcVersion, loaderVersion)
+	if err != nil {
+		return err
+	}
+
+	// Download libraries
+	var classpath []string
+	for _, library := range profile.Libraries {
+		path, err := library.Path()
+		if err != nil {
+			return err
+		}
+		if err := downloadLibrary(ctx, dest, library); err != nil {
+			return err
+		}
+		classpath = append(classpath, "libraries/"+path)
+	}
+
+	if target == minecraft.Client {
+		// Save version info to disk
+		versionDir := filepath.Join(dest, "versions", versionName)
+		if err := os.MkdirAll(versionDir, os.ModePerm); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(versionDir, versionName+".json"), profile.raw, 0644); err != nil {
+			return err
+		}
+
+		// Older launchers expect a jar alongside the version
+		return ioutil.WriteFile(filepath.Join(versionDir, versionName+".jar"), nil, 0644)
+	}
+
+	// The server launcher needs the vanilla server alongside it
+	if _, err := os.Stat(filepath.Join(dest, serverJar)); os.IsNotExist(err) {
+		fmt.Println("Downloading Minecraft " + mcVersion.String() + " server jar...")
+		if err := manifest.DownloadServer(ctx, mcVersion.String(), filepath.Join(dest, serverJar)); err != nil {
+			return err
+		}
+	}
+	return writeServerLaunchJar(filepath.Join(dest, loader.ServerLaunchJar), loader, profile, classpath)
+}
+
+// Downloads the given library, to the libraries directory within the
+// given destination - should it not already exist.
+func downloadLibrary(ctx context.Context, dest string, library *Library) error {
+	path, err := library.Path()
+	if err != nil {
+		return err
+	}
+	libraryPath := filepath.Join(dest, "libraries", filepath.FromSlash(path))
+	if _, err := os.Stat(libraryPath); err == nil {
+		return nil
+	}
+
+	u, err := library.DownloadURL()
+	if err != nil {
+		return err
+	}
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/java-archive")
+
+	_, err = util.DownloadFile(req, libraryPath, library.Sha1, library.Size)
+	return err
+}
+
+// Writes the jar that servers are launched with, which puts the given
+// libraries on the classpath before launching the loader.
+func writeServerLaunchJar(path string, loader *Loader, profile *Profile, classpath []string) error {
+	launcherMainClass := profile.LauncherMainClass
+	if launcherMainClass == "" {
+		launcherMainClass = loader.LauncherMainClass
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+
+	mf, err := w.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		return err
+	}
+	if _, err := mf.Write([]byte(manifestAttribute("Manifest-Version", "1.0") +
+		manifestAttribute("Main-Class", launcherMainClass) +
+		manifestAttribute("Class-Path", strings.Join(classpath, " ")) +
+		"\r\n")); err != nil {
+		return err
+	}
+
+	// Read by the server launcher, from within its own jar
+	properties, err := w.Create(strings.TrimSuffix(loader.ServerLaunchJar, ".jar") + ".properties")
+	if err != nil {
+		return err
+	}
+	if _, err := properties.Write([]byte("launch.mainClass=" + profile.MainClass + "\n")); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+// Formats an attribute of a jar manifest, where lines may be no longer than
+// 72 bytes - continuing on lines starting with a space.
+func manifestAttribute(name string, value string) string {
+	line := name + ": " + value
+	var b strings.Builder
+	for len(line) > 72 {
+		b.WriteString(line[:72] + "\r\n")
+		line = " " + line[72:]
+	}
+	b.WriteString(line + "\r\n")
+	return b.String()
+}
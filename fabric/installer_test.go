@@ -0,0 +1,137 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fabric
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+// Serves a mock of Fabric's meta API, and Maven
+func newMockServer() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/versions/loader/1.19.2/0.14.9/profile/json":
+			fmt.Fprintf(w, `{"id": "fabric-loader-0.14.9-1.19.2", "inheritsFrom": "1.19.2", "mainClass": "net.fabricmc.loader.impl.launch.knot.KnotClient", "libraries": [
+				{"name": "net.fabricmc:fabric-loader:0.14.9", "url": "%s/maven/"}
+			]}`, server.URL)
+		case "/v2/versions/loader/1.19.2/0.14.9/server/json":
+			fmt.Fprintf(w, `{"id": "fabric-loader-0.14.9-1.19.2", "mainClass": "net.fabricmc.loader.impl.launch.knot.KnotServer", "launcherMainClass": "net.fabricmc.loader.impl.launch.server.FabricServerLauncher", "libraries": [
+				{"name": "net.fabricmc:fabric-loader:0.14.9", "url": "%s/maven/"},
+				{"name": "net.fabricmc:intermediary:1.19.2", "url": "%s/maven"}
+			]}`, server.URL, server.URL)
+		case "/maven/net/fabricmc/fabric-loader/0.14.9/fabric-loader-0.14.9.jar",
+			"/maven/net/fabricmc/intermediary/1.19.2/intermediary-1.19.2.jar":
+			fmt.Fprint(w, "jar")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestInstallFabric(t *testing.T) {
+	server := newMockServer()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "fabricinstall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	installer := NewInstaller()
+	installer.MetaRoot, _ = url.Parse(server.URL + "/")
+	mcVersion, _ := minecraft.ParseVersion("1.19.2")
+
+	// Client
+	{
+		launcherDir := filepath.Join(dir, "launcher")
+		if err := installer.InstallFabric(minecraft.Client, launcherDir, mcVersion, "0.14.9"); err != nil {
+			t.Fatal(err)
+		}
+		versionJson, err := ioutil.ReadFile(filepath.Join(launcherDir, "versions", "fabric-loader-0.14.9-1.19.2", "fabric-loader-0.14.9-1.19.2.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(versionJson), "KnotClient") {
+			t.Errorf("unexpected version json %s", versionJson)
+		}
+		if _, err := os.Stat(filepath.Join(launcherDir, "libraries", "net", "fabricmc", "fabric-loader", "0.14.9", "fabric-loader-0.14.9.jar")); err != nil {
+			t.Errorf("library wasn't downloaded: %v", err)
+		}
+	}
+
+	// Server, where the vanilla server is already in place
+	{
+		serverDir := filepath.Join(dir, "server")
+		if err := os.MkdirAll(serverDir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(serverDir, serverJar), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := installer.InstallFabric(minecraft.Server, serverDir, mcVersion, "0.14.9"); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := zip.OpenReader(filepath.Join(serverDir, ServerLaunchJar))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		contents := readZipFile(t, &r.Reader, "META-INF/MANIFEST.MF")
+		manifest := strings.Replace(contents, "\r\n ", "", -1)
+		if !strings.Contains(manifest, "Main-Class: net.fabricmc.loader.impl.launch.server.FabricServerLauncher") ||
+			!strings.Contains(manifest, "Class-Path: libraries/net/fabricmc/fabric-loader/0.14.9/fabric-loader-0.14.9.jar libraries/net/fabricmc/intermediary/1.19.2/intermediary-1.19.2.jar\r\n") {
+			t.Errorf("unexpected manifest %q", contents)
+		}
+		for _, line := range strings.Split(contents, "\r\n") {
+			if len(line) > 72 {
+				t.Errorf("manifest line is longer than 72 bytes: %q", line)
+			}
+		}
+		if properties := readZipFile(t, &r.Reader, "fabric-server-launch.properties"); properties != "launch.mainClass=net.fabricmc.loader.impl.launch.knot.KnotServer\n" {
+			t.Errorf("unexpected properties %q", properties)
+		}
+	}
+
+	// Unknown versions
+	if _, err := installer.GetProfile(context.Background(), minecraft.Client, mcVersion, "0.0.0"); err == nil {
+		t.Error("expected an unknown version to fail")
+	}
+}
+
+func readZipFile(t *testing.T, r *zip.Reader, name string) string {
+	for _, file := range r.File {
+		if file.Name != name {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		contents, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(contents)
+	}
+	t.Fatalf("%s not found", name)
+	return ""
+}
@@ -0,0 +1,116 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package fabric
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+// Profile is a launcher version of the loader, for either the client or
+// the server, as given by the meta API.
+type Profile struct {
+	ID           string     `json:"id"`
+	InheritsFrom string     `json:"inheritsFrom"`
+	MainClass    string     `json:"mainClass"`
+	Libraries    []*Library `json:"libraries"`
+
+	// The main class of the server launcher, for server profiles
+	LauncherMainClass string `json:"launcherMainClass"`
+
+	// The profile, as given by the meta API
+	raw []byte
+}
+
+// Library is a library required by the loader.
+type Library struct {
+	// The Maven artifact, for example net.fabricmc:fabric-loader:0.14.9
+	Name string `json:"name"`
+
+	// The root of the Maven repository that hosts the library
+	URL string `json:"url"`
+
+	// The sha1 hash and size of the library, should they be known
+	Sha1 string `json:"sha1"`
+	Size int64  `json:"size"`
+}
+
+// Path gets the path of the library, relative to the libraries directory.
+func (l *Library) Path() (string, error) {
+	return util.MavenPath(l.Name)
+}
+
+// DownloadURL gets the URL to download the library from.
+func (l *Library) DownloadURL() (string, error) {
+	path, err := l.Path()
+	if err != nil {
+		return "", err
+	}
+	root := l.URL
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	return root + path, nil
+}
+
+// ProfileURL gets the URL of the profile of the given version of the
+// loader, for the given install target.
+func (i *Installer) ProfileURL(target minecraft.InstallTarget, mcVersion *minecraft.Version, loaderVersion string) (*url.URL, error) {
+	kind := "profile"
+	if target == minecraft.Server {
+		kind = "server"
+	}
+	return i.MetaRoot.Parse(i.loader().MetaVersion + "/versions/loader/" + url.PathEscape(mcVersion.String()) + "/" + url.PathEscape(loaderVersion) + "/" + kind + "/json")
+}
+
+// GetProfile gets the profile of the given version of the loader, for the
+// given install target.
+func (i *Installer) GetProfile(ctx context.Context, target minecraft.InstallTarget, mcVersion *minecraft.Version, loaderVersion string) (*Profile, error) {
+	u, err := i.ProfileURL(target, mcVersion, loaderVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the request
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// Make the request
+	resp, err := util.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		return nil, fmt.Errorf("%w: %s %s for Minecraft %s", ErrVersionNotFound, i.loader().Name, loaderVersion, mcVersion)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fabric: failed to get profile: %s", resp.Status)
+	}
+
+	// Get the profile
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	profile := &Profile{
+		raw: raw,
+	}
+	if err := json.Unmarshal(raw, profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
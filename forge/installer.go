@@ -5,30 +5,51 @@
 package forge
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 
 	"github.com/jamiemansfield/mcinstall/minecraft"
 	"github.com/jamiemansfield/mcinstall/util"
 )
 
 const (
-	defaultMavenRoot = "https://files.minecraftforge.net/maven/"
+	defaultMavenRoot         = "https://files.minecraftforge.net/maven/"
+	defaultNeoForgeMavenRoot = "https://maven.neoforged.net/releases/"
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("forge: unsupported version of Minecraft")
 )
 
 type Installer struct {
 	// The URL to Minecraft Forge's Maven, or a mirror.
 	MavenRoot *url.URL
+
+	// The URL to NeoForge's Maven, or a mirror.
+	NeoForgeMavenRoot *url.URL
+
+	// The URL that FML downloads its libraries from, or a mirror.
+	FMLLibrariesRoot *url.URL
 }
 
 // NewInstaller returns a new Installer to use for installing Minecraft
 // Forge.
 func NewInstaller() *Installer {
 	mavenRoot, _ := url.Parse(defaultMavenRoot)
+	neoForgeMavenRoot, _ := url.Parse(defaultNeoForgeMavenRoot)
+	fmlLibrariesRoot, _ := url.Parse(defaultFMLLibrariesRoot)
 
 	return &Installer{
-		MavenRoot: mavenRoot,
+		MavenRoot:         mavenRoot,
+		NeoForgeMavenRoot: neoForgeMavenRoot,
+		FMLLibrariesRoot:  fmlLibrariesRoot,
 	}
 }
 
@@ -37,39 +58,119 @@ func NewInstaller() *Installer {
 // the server; if the target is Client, the destination will be the
 // launcher's root directory.
 func (i *Installer) InstallForge(target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, forgeVersion string) error {
+	return i.InstallForgeContext(context.Background(), target, dest, mcVersion, forgeVersion)
+}
+
+// See InstallForge
+// Should the context be cancelled, any in-progress download will be
+// aborted and the Forge installer process killed.
+func (i *Installer) InstallForgeContext(ctx context.Context, target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, forgeVersion string) error {
 	forgeVrsn, err := ParseVersion(forgeVersion)
 	if err != nil {
 		return err
 	}
 
 	// Use modern installer - Minecraft 1.13 and above / newer Minecraft 1.12 builds
-	if (mcVersion.Major >= 1 && mcVersion.Minor >= 13) || (mcVersion.Minor == 12 && forgeVrsn.Build >= 2851) {
-		return i.installModernForge(target, dest, mcVersion, forgeVersion)
+	if usesModernInstaller(mcVersion, forgeVrsn) {
+		return i.installModernForge(ctx, target, dest, mcVersion, forgeVersion)
 	} else
 	// Use universal install method - Minecraft 1.5 -> Minecraft 1.12
 	if mcVersion.Major >= 1 && mcVersion.Minor >= 5 && mcVersion.Minor <= 12 {
-		return i.installUniversalForge(target, dest, mcVersion, forgeVersion)
+		return i.installUniversalForge(ctx, target, dest, mcVersion, forgeVersion)
+	} else
+	// Use jar mod install method - Minecraft 1.2.5 -> Minecraft 1.4.7
+	if IsLegacy(mcVersion) {
+		return i.installLegacyForge(ctx, target, dest, mcVersion, forgeVersion)
 	}
-	// todo: older
 
-	return nil
+	return ErrUnsupportedVersion
 }
 
-// Downloads the Minecraft Forge installer for the given version (MC-Forge),
-// to a temporary file.
+// Determines whether the given version of Minecraft Forge is installed
+// using the modern installer.
+func usesModernInstaller(mcVersion *minecraft.Version, forgeVersion *Version) bool {
+	return (mcVersion.Major >= 1 && mcVersion.Minor >= 13) || (mcVersion.Minor == 12 && forgeVersion.Build >= 2851)
+}
+
+// ServerJar gets the name of the jar that a server, with the given version
+// of Minecraft Forge installed, is launched with. Servers for Minecraft 1.17
+// and above are instead launched with the run scripts created by the Forge
+// installer, so no jar is given.
+func ServerJar(mcVersion *minecraft.Version, forgeVersion string) (string, error) {
+	forgeVrsn, err := ParseVersion(forgeVersion)
+	if err != nil {
+		return "", err
+	}
+	version := mcVersion.String() + "-" + forgeVersion
+
+	if mcVersion.Major >= 1 && mcVersion.Minor >= 17 {
+		return "", nil
+	}
+	if usesModernInstaller(mcVersion, forgeVrsn) {
+		return "forge-" + version + ".jar", nil
+	}
+	return "forge-" + version + "-universal.jar", nil
+}
+
+// DownloadInstaller downloads the Minecraft Forge installer for the given
+// version (MC-Forge), to a temporary file.
 // The temporary file should be removed after usage.
-func (i *Installer) downloadForgeInstaller(version string) (*os.File, error) {
-	u, err := i.MavenRoot.Parse("net/minecraftforge/forge/" + version + "/forge-" + version + "-installer.jar")
+func (i *Installer) DownloadInstaller(ctx context.Context, version string) (*os.File, error) {
+	u, err := i.InstallerURL(version)
+	if err != nil {
+		return nil, err
+	}
+	return downloadInstaller(ctx, u)
+}
+
+// Downloads the installer at the given URL, to a temporary file.
+// The temporary file should be removed after usage.
+func downloadInstaller(ctx context.Context, u *url.URL) (*os.File, error) {
+	// Forge's Maven publishes the sha1 hash of the installer alongside it
+	sha1, err := getMavenSha1(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
 	// Download installer
-	req, err := util.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/java,application/java-archive,application/x-java-archive")
 
-	return util.DownloadTemp(req, "forge*.jar")
+	return util.DownloadTempVerified(req, "forge*.jar", sha1, 0)
+}
+
+// Gets the sha1 hash published for the given Maven artifact, or an empty
+// string should one not have been published.
+func getMavenSha1(ctx context.Context, artifact *url.URL) (string, error) {
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, artifact.String()+".sha1", nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := util.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("forge: failed to get sha1 of %s: %s", artifact, resp.Status)
+	}
+
+	contents, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+
+	// Some files contain the file name after the hash
+	fields := strings.Fields(string(contents))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToLower(fields[0]), nil
 }
@@ -0,0 +1,290 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package forge
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/minecraft/launcher"
+	"github.com/jamiemansfield/mcinstall/minecraft/manifest"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	defaultFMLLibrariesRoot = "https://files.minecraftforge.net/fmllibs/"
+
+	// The directory, within the game directory, that FML loads its
+	// libraries from
+	fmlLibrariesDir = "lib"
+)
+
+// The libraries FML downloads when first launched, and their sha1 hashes.
+var (
+	fmlLibraries13 = [][2]string{
+		{"argo-2.25.jar", "bb672829fde76cb163004752b86b0484bd0a7f4b"},
+		{"guava-12.0.1.jar", "b8e78b9af7bf45900e14c6f958486b6ca682195f"},
+		{"asm-all-4.0.jar", "98308890597acb64047f7e896638e0d98753ae82"},
+	}
+	fmlLibraries14 = [][2]string{
+		{"argo-2.25.jar", "bb672829fde76cb163004752b86b0484bd0a7f4b"},
+		{"guava-12.0.1.jar", "b8e78b9af7bf45900e14c6f958486b6ca682195f"},
+		{"asm-all-4.0.jar", "98308890597acb64047f7e896638e0d98753ae82"},
+		{"bcprov-jdk15on-147.jar", "b6f5d9926b0afbde9f4dbe3db88c5247be7794bb"},
+	}
+)
+
+// IsLegacy reports whether Minecraft Forge is installed as a jar mod for
+// the given version of Minecraft, as it is for Minecraft 1.2.5 -> 1.4.7.
+func IsLegacy(mcVersion *minecraft.Version) bool {
+	return mcVersion.Major == 1 && mcVersion.Minor < 5 &&
+		(mcVersion.Minor > 2 || (mcVersion.Minor == 2 && mcVersion.Revision >= 5))
+}
+
+// LegacyURL gets the URL of the zip of Minecraft Forge to merge into the
+// given target's jar, for the given version (MC-Forge). Minecraft 1.3 and
+// above share a universal zip between the client and server.
+func (i *Installer) LegacyURL(target minecraft.InstallTarget, mcVersion *minecraft.Version, forgeVersion string) (*url.URL, error) {
+	version := mcVersion.String() + "-" + forgeVersion
+	classifier := "universal"
+	if mcVersion.Minor < 3 && target == minecraft.Server {
+		classifier = "server"
+	} else if mcVersion.Minor < 3 {
+		classifier = "client"
+	}
+	return i.MavenRoot.Parse("net/minecraftforge/forge/" + version + "/forge-" + version + "-" + classifier + ".zip")
+}
+
+// FMLLibraries gets the libraries that FML would download when first
+// launched, for the given version of Minecraft. The path of each library
+// is relative to the lib directory within the game directory.
+func (i *Installer) FMLLibraries(mcVersion *minecraft.Version) ([]*Library, error) {
+	var files [][2]string
+	if mcVersion.Major == 1 && mcVersion.Minor == 3 {
+		files = fmlLibraries13
+	} else if mcVersion.Major == 1 && mcVersion.Minor == 4 {
+		files = fmlLibraries14
+	}
+
+	var libraries []*Library
+	for _, file := range files {
+		u, err := i.FMLLibrariesRoot.Parse(file[0])
+		if err != nil {
+			return nil, err
+		}
+		libraries = append(libraries, &Library{
+			Name:      file[0],
+			Path:      file[0],
+			URL:       u.String(),
+			Sha1:      file[1],
+			ClientReq: true,
+			ServerReq: true,
+		})
+	}
+	return libraries, nil
+}
+
+// Installs the libraries FML would download when first launched, to the
+// given game directory. The servers that FML downloaded from no longer
+// exist, so Minecraft Forge can't be launched without them.
+func (i *Installer) InstallFMLLibraries(dest string, mcVersion *minecraft.Version) error {
+	return i.InstallFMLLibrariesContext(context.Background(), dest, mcVersion)
+}
+
+// See InstallFMLLibraries
+// Should the context be cancelled, any in-progress download will be
+// aborted.
+func (i *Installer) InstallFMLLibrariesContext(ctx context.Context, dest string, mcVersion *minecraft.Version) error {
+	libraries, err := i.FMLLibraries(mcVersion)
+	if err != nil {
+		return err
+	}
+
+	for _, library := range libraries {
+		path := filepath.Join(dest, fmlLibrariesDir, library.Path)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		fmt.Println("Downloading FML library " + library.Name + "...")
+
+		req, err := util.NewRequestWithContext(ctx, http.MethodGet, library.URL, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/java-archive")
+		if _, err := util.DownloadFile(req, path, library.Sha1, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// See InstallForge
+// Installs Minecraft Forge for Minecraft 1.2.5 -> 1.4.7, merging it into
+// the client jar (as a new launcher version) or the server jar.
+func (i *Installer) installLegacyForge(ctx context.Context, target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, forgeVersion string) error {
+	version := mcVersion.String() + "-" + forgeVersion
+	versionName := mcVersion.String() + "-forge" + version
+
+	// Check whether we need to install Minecraft Forge
+	_, serverCheck := os.Stat(filepath.Join(dest,
+		"forge-"+version+"-universal.jar",
+	))
+	_, clientCheck := os.Stat(filepath.Join(dest,
+		"versions", versionName, versionName+".jar",
+	))
+	if (serverCheck == nil && target == minecraft.Server) ||
+		(clientCheck == nil && target == minecraft.Client) {
+		fmt.Println("Minecraft Forge install found, skipping...")
+		return nil
+	}
+	fmt.Printf("Installing Minecraft Forge %s as a jar mod...\n", version)
+
+	// Download Minecraft Forge
+	u, err := i.LegacyURL(target, mcVersion, forgeVersion)
+	if err != nil {
+		return err
+	}
+	forgeZip, err := downloadLegacy(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		forgeZip.Close()
+		os.Remove(forgeZip.Name())
+	}()
+
+	if target == minecraft.Client {
+		// Ensure the vanilla client exists, to merge into
+		if err := launcher.InstallClientVersionContext(ctx, dest, mcVersion.String()); err != nil {
+			return err
+		}
+		versionDir := filepath.Join(dest, "versions", versionName)
+		if err := os.MkdirAll(versionDir, os.ModePerm); err != nil {
+			return err
+		}
+
+		// Minecraft before 1.6 is an applet, launched with LegacyLaunch
+		legacyLaunch, mainClass, err := launcher.InstallLegacyLaunch(dest)
+		if err != nil {
+			return err
+		}
+		versionFile, err := os.Create(filepath.Join(versionDir, versionName+".json"))
+		if err != nil {
+			return err
+		}
+		defer versionFile.Close()
+		encoder := json.NewEncoder(versionFile)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(&launcher.Version{
+			ID:           versionName,
+			Type:         "release",
+			InheritsFrom: mcVersion.String(),
+			MainClass:    mainClass,
+			Libraries:    []*launcher.VersionLibrary{legacyLaunch},
+		}); err != nil {
+			return err
+		}
+
+		// The client jar is signed, so its signatures are removed along
+		// with the rest of META-INF
+		clientJar := filepath.Join(dest, "versions", mcVersion.String(), mcVersion.String()+".jar")
+		return mergeLegacyJar(filepath.Join(versionDir, versionName+".jar"), forgeZip, clientJar, true)
+	}
+
+	// The vanilla server isn't signed, and its manifest is needed to
+	// launch it
+	serverJar := filepath.Join(dest, "minecraft_server."+mcVersion.String()+".jar")
+	if _, err := os.Stat(serverJar); os.IsNotExist(err) {
+		fmt.Println("Downloading Minecraft " + mcVersion.String() + " server jar...")
+		if err := manifest.DownloadServer(ctx, mcVersion.String(), serverJar); err != nil {
+			return err
+		}
+	}
+	if err := mergeLegacyJar(filepath.Join(dest, "forge-"+version+"-universal.jar"), forgeZip, serverJar, false); err != nil {
+		return err
+	}
+	return i.InstallFMLLibrariesContext(ctx, dest, mcVersion)
+}
+
+// Downloads the zip of Minecraft Forge at the given URL, to a temporary
+// file.
+// The temporary file should be removed after usage.
+func downloadLegacy(ctx context.Context, u *url.URL) (*os.File, error) {
+	sha1, err := getMavenSha1(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/zip")
+
+	return util.DownloadTempVerified(req, "forge*.zip", sha1, 0)
+}
+
+// Merges the zip of Minecraft Forge into the given jar, creating a new
+// jar at the given path. Files in Minecraft Forge replace those of the
+// jar, and Minecraft Forge's own META-INF is always left out.
+// The new jar is only moved into place once complete, as its existence
+// is taken to mean that Minecraft Forge has been installed.
+func mergeLegacyJar(path string, forgeZip *os.File, jarPath string, stripJarMeta bool) error {
+	forgeInfo, err := forgeZip.Stat()
+	if err != nil {
+		return err
+	}
+	forge, err := zip.NewReader(forgeZip, forgeInfo.Size())
+	if err != nil {
+		return err
+	}
+	jarFile, err := zip.OpenReader(jarPath)
+	if err != nil {
+		return err
+	}
+	defer jarFile.Close()
+
+	partPath := path + util.PartSuffix
+	f, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(partPath)
+	}()
+	zw := zip.NewWriter(f)
+
+	isMeta := func(name string) bool {
+		return strings.HasPrefix(name, "META-INF/")
+	}
+	files, err := util.MergeZips(zw, forge, nil, isMeta)
+	if err != nil {
+		return err
+	}
+	var jarFilter func(string) bool
+	if stripJarMeta {
+		jarFilter = isMeta
+	}
+	if _, err := util.MergeZips(zw, &jarFile.Reader, files, jarFilter); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partPath, path)
+}
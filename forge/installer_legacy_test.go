@@ -0,0 +1,131 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package forge
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+func TestLegacyURL(t *testing.T) {
+	installer := NewInstaller()
+
+	// 1.2.5, with separate client and server zips
+	{
+		mcVersion, _ := minecraft.ParseVersion("1.2.5")
+		if !IsLegacy(mcVersion) {
+			t.Errorf("1.2.5 should be legacy")
+		}
+		u, err := installer.LegacyURL(minecraft.Server, mcVersion, "3.4.9.171")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != "https://files.minecraftforge.net/maven/net/minecraftforge/forge/1.2.5-3.4.9.171/forge-1.2.5-3.4.9.171-server.zip" {
+			t.Errorf("unexpected url %s", u)
+		}
+	}
+
+	// 1.4.7
+	{
+		mcVersion, _ := minecraft.ParseVersion("1.4.7")
+		if !IsLegacy(mcVersion) {
+			t.Errorf("1.4.7 should be legacy")
+		}
+		u, err := installer.LegacyURL(minecraft.Client, mcVersion, "6.6.2.534")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != "https://files.minecraftforge.net/maven/net/minecraftforge/forge/1.4.7-6.6.2.534/forge-1.4.7-6.6.2.534-universal.zip" {
+			t.Errorf("unexpected url %s", u)
+		}
+		libraries, err := installer.FMLLibraries(mcVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(libraries) != 4 || libraries[3].URL != "https://files.minecraftforge.net/fmllibs/bcprov-jdk15on-147.jar" {
+			t.Errorf("unexpected libraries %+v", libraries)
+		}
+	}
+
+	// 1.2.4 and 1.5.2 aren't
+	for _, version := range []string{"1.2.4", "1.5.2"} {
+		mcVersion, _ := minecraft.ParseVersion(version)
+		if IsLegacy(mcVersion) {
+			t.Errorf("%s shouldn't be legacy", version)
+		}
+	}
+}
+
+func TestMergeLegacyJar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mcinstall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	forgeZip := writeZip(t, filepath.Join(dir, "forge.zip"), map[string]string{
+		"META-INF/MANIFEST.MF":  "forge",
+		"net/minecraft/a.class": "forge",
+	})
+	defer forgeZip.Close()
+	writeZip(t, filepath.Join(dir, "client.jar"), map[string]string{
+		"META-INF/MOJANG_C.SF":  "signature",
+		"net/minecraft/a.class": "vanilla",
+		"net/minecraft/b.class": "vanilla",
+	}).Close()
+
+	path := filepath.Join(dir, "merged.jar")
+	if err := mergeLegacyJar(path, forgeZip, filepath.Join(dir, "client.jar"), true); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer merged.Close()
+	files := map[string]string{}
+	for _, file := range merged.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(contents)
+	}
+	if len(files) != 2 || files["net/minecraft/a.class"] != "forge" || files["net/minecraft/b.class"] != "vanilla" {
+		t.Errorf("unexpected merged jar %v", files)
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) *os.File {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for name, contents := range files {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
@@ -8,9 +8,11 @@ package forge
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
 
@@ -18,27 +20,58 @@ import (
 	"github.com/jamiemansfield/mcinstall/util"
 )
 
+// A build of Minecraft Forge (or NeoForge) installed using the modern
+// installer.
+type modernBuild struct {
+	// The name of the modloader, and its version
+	name    string
+	version string
+
+	// Where to download the installer from
+	installerURL *url.URL
+
+	// Files that exist once the build has been installed, relative to the
+	// server's root directory, and the launcher's root directory.
+	serverCheck string
+	clientCheck string
+
+	// Whether the installer can install the client itself, rather than
+	// needing the Forge Client Installer tool.
+	installsClient bool
+}
+
 // See InstallForge
 // Installs Minecraft Forge for Minecraft >= 1.13
-func (i *Installer) installModernForge(target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, forgeVersion string) error {
+func (i *Installer) installModernForge(ctx context.Context, target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, forgeVersion string) error {
 	version := mcVersion.String() + "-" + forgeVersion
+	installerURL, err := i.InstallerURL(version)
+	if err != nil {
+		return err
+	}
+
+	return i.installModern(ctx, target, dest, &modernBuild{
+		name:         "Minecraft Forge",
+		version:      version,
+		installerURL: installerURL,
+		serverCheck:  "forge-" + version + ".jar",
+		clientCheck:  "libraries/net/minecraftforge/forge/" + version + "/forge-" + version + ".jar",
+	})
+}
 
-	// Check whether we need to install Minecraft Forge
-	_, serverCheck := os.Stat(filepath.Join(dest,
-		"forge-"+version+".jar",
-	))
-	_, clientCheck := os.Stat(filepath.Join(dest,
-		"libraries", "net", "minecraftforge", "forge", version, "forge-"+version+".jar",
-	))
+// Installs the given build using the modern installer.
+func (i *Installer) installModern(ctx context.Context, target minecraft.InstallTarget, dest string, build *modernBuild) error {
+	// Check whether we need to install the build
+	_, serverCheck := os.Stat(filepath.Join(dest, filepath.FromSlash(build.serverCheck)))
+	_, clientCheck := os.Stat(filepath.Join(dest, filepath.FromSlash(build.clientCheck)))
 	if (serverCheck == nil && target == minecraft.Server) ||
 		(clientCheck == nil && target == minecraft.Client) {
-		fmt.Println("Minecraft Forge install found, skipping...")
+		fmt.Println(build.name + " install found, skipping...")
 		return nil
 	}
-	fmt.Printf("Installing Minecraft Forge %s-%s using modern installer...\n", mcVersion, forgeVersion)
+	fmt.Printf("Installing %s %s using modern installer...\n", build.name, build.version)
 
 	// Download installer
-	installerJar, err := i.downloadForgeInstaller(version)
+	installerJar, err := downloadInstaller(ctx, build.installerURL)
 	if err != nil {
 		return err
 	}
@@ -49,7 +82,9 @@ func (i *Installer) installModernForge(target minecraft.InstallTarget, dest stri
 
 	// Create the appropriate arguments for the install target
 	var args []string
-	if target == minecraft.Client {
+	if target == minecraft.Client && build.installsClient {
+		args = append(args, "-jar", installerJar.Name(), "--installClient", dest)
+	} else if target == minecraft.Client {
 		toolJar, err := copyClientInstallTool()
 		if err != nil {
 			return err
@@ -62,7 +97,7 @@ func (i *Installer) installModernForge(target minecraft.InstallTarget, dest stri
 	}
 
 	// Run installer
-	return util.RunCommand("java", args...)
+	return util.RunCommandContext(ctx, "java", args...)
 }
 
 // Downloads the Forge Client Installer tool.
@@ -6,6 +6,7 @@ package forge
 
 import (
 	"archive/zip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,7 +19,7 @@ import (
 
 // See InstallForge
 // Installs Minecraft Forge for Minecraft 1.5 -> 1.12
-func (i *Installer) installUniversalForge(target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, forgeVersion string) error {
+func (i *Installer) installUniversalForge(ctx context.Context, target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, forgeVersion string) error {
 	fmt.Println("Using universal Forge installer...")
 	version := mcVersion.String() + "-" + forgeVersion
 
@@ -36,7 +37,7 @@ func (i *Installer) installUniversalForge(target minecraft.InstallTarget, dest s
 	}
 
 	// Download installer
-	installerJar, err := i.downloadForgeInstaller(version)
+	installerJar, err := i.DownloadInstaller(ctx, version)
 	if err != nil {
 		return err
 	}
@@ -95,14 +96,31 @@ func (i *Installer) installUniversalForge(target minecraft.InstallTarget, dest s
 			return err
 		}
 
-		// Save Forge universal jar to disk
+		// Download the libraries needed by the client, rather than leaving
+		// them to the launcher - as third-party and offline launchers may
+		// not download them
+		libraries, err := GetInstallerLibraries(reader)
+		if err != nil {
+			return err
+		}
+		for _, library := range libraries {
+			if !library.ClientReq {
+				continue
+			}
+			if err := downloadLibrary(ctx, dest, library); err != nil {
+				return err
+			}
+		}
+
+		// Save Forge universal jar to disk, last - as its existence is taken
+		// to mean that Minecraft Forge has been installed
 		universalJar, err := util.GetFileInZip(reader, "forge-"+version+"-universal.jar")
 		if err != nil {
 			return err
 		}
 		return util.CopyZipFileToDisk(universalJar, filepath.Join(libraryDir, "forge-"+version+".jar"))
 	} else {
-		return util.RunCommand("java", "-jar", installerJar.Name(), "--installServer", dest)
+		return util.RunCommandContext(ctx, "java", "-jar", installerJar.Name(), "--installServer", dest)
 	}
 }
 
@@ -0,0 +1,256 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package forge
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	defaultLibrariesRoot = "https://libraries.minecraft.net/"
+)
+
+// Library is a library required by a version of Minecraft Forge, that the
+// Forge installer (or the launcher) will download.
+type Library struct {
+	// The Maven artifact, for example net.minecraftforge:forge:1.12.2-14.23.5.2860
+	Name string
+
+	// The path of the library, relative to the libraries directory
+	Path string
+
+	// Where to download the library from, and its sha1 hash - should it be
+	// known.
+	URL  string
+	Sha1 string
+
+	// The sha1 hashes the library may match, for libraries of the universal
+	// installer - which may give more than one. Should only one be given,
+	// it is also the library's Sha1.
+	Checksums []string
+
+	// Whether the library is required by the client, and the server
+	ClientReq bool
+	ServerReq bool
+}
+
+// InstallerURL gets the URL of the Minecraft Forge installer for the given
+// version (MC-Forge).
+func (i *Installer) InstallerURL(version string) (*url.URL, error) {
+	return i.MavenRoot.Parse("net/minecraftforge/forge/" + version + "/forge-" + version + "-installer.jar")
+}
+
+type profileLibrary struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Downloads struct {
+		Artifact *struct {
+			Path string `json:"path"`
+			URL  string `json:"url"`
+			Sha1 string `json:"sha1"`
+		} `json:"artifact"`
+	} `json:"downloads"`
+
+	// Only used by the universal installer, where a nil value is true
+	ClientReq *bool    `json:"clientreq"`
+	ServerReq *bool    `json:"serverreq"`
+	Checksums []string `json:"checksums"`
+}
+
+// GetInstallerLibraries gets the libraries that the given Minecraft Forge
+// installer will download. Libraries contained within the installer itself
+// are left out.
+func GetInstallerLibraries(installer *zip.Reader) ([]*Library, error) {
+	var profile struct {
+		// Modern installer
+		Json      string            `json:"json"`
+		Libraries []*profileLibrary `json:"libraries"`
+
+		// Universal installer
+		VersionInfo *struct {
+			Libraries []*profileLibrary `json:"libraries"`
+		} `json:"versionInfo"`
+	}
+	if err := readZipJson(installer, "install_profile.json", &profile); err != nil {
+		return nil, err
+	}
+
+	// Universal installer
+	if profile.VersionInfo != nil {
+		var libraries []*Library
+		for _, lib := range profile.VersionInfo.Libraries {
+			// Forge itself is within the installer
+			if strings.HasPrefix(lib.Name, "net.minecraftforge:forge:") {
+				continue
+			}
+
+			path, err := util.MavenPath(lib.Name)
+			if err != nil {
+				return nil, err
+			}
+			root := lib.URL
+			if root == "" {
+				root = defaultLibrariesRoot
+			}
+			if !strings.HasSuffix(root, "/") {
+				root += "/"
+			}
+			var sha1 string
+			if len(lib.Checksums) == 1 {
+				sha1 = lib.Checksums[0]
+			}
+			libraries = append(libraries, &Library{
+				Name:      lib.Name,
+				Path:      path,
+				URL:       root + path,
+				Sha1:      sha1,
+				Checksums: lib.Checksums,
+				ClientReq: lib.ClientReq == nil || *lib.ClientReq,
+				ServerReq: lib.ServerReq == nil || *lib.ServerReq,
+			})
+		}
+		return libraries, nil
+	}
+
+	// Modern installer, the libraries needed to run the installer's
+	// processors and those of the version itself
+	libs := profile.Libraries
+	if profile.Json != "" {
+		var version struct {
+			Libraries []*profileLibrary `json:"libraries"`
+		}
+		if err := readZipJson(installer, strings.TrimPrefix(profile.Json, "/"), &version); err != nil {
+			return nil, err
+		}
+		libs = append(libs, version.Libraries...)
+	}
+
+	var libraries []*Library
+	seen := map[string]bool{}
+	for _, lib := range libs {
+		artifact := lib.Downloads.Artifact
+		if artifact == nil || artifact.URL == "" || seen[artifact.Path] {
+			continue
+		}
+		seen[artifact.Path] = true
+
+		libraries = append(libraries, &Library{
+			Name:      lib.Name,
+			Path:      artifact.Path,
+			URL:       artifact.URL,
+			Sha1:      artifact.Sha1,
+			ClientReq: true,
+			ServerReq: true,
+		})
+	}
+	return libraries, nil
+}
+
+// GetInstallerMappings gets where the given Minecraft Forge installer's
+// processors expect Mojang's client and server mappings, relative to the
+// libraries directory. Should the installer not use the mappings (as
+// before Minecraft 1.17), empty paths are returned.
+func GetInstallerMappings(installer *zip.Reader) (string, string, error) {
+	var profile struct {
+		Data map[string]struct {
+			Client string `json:"client"`
+			Server string `json:"server"`
+		} `json:"data"`
+	}
+	if err := readZipJson(installer, "install_profile.json", &profile); err != nil {
+		return "", "", err
+	}
+
+	mappings, ok := profile.Data["MOJMAPS"]
+	if !ok {
+		return "", "", nil
+	}
+
+	// Artifacts are given in brackets, for example
+	// [net.minecraft:client:1.19.2-20220805.130853:mappings@txt]
+	artifactPath := func(value string) (string, error) {
+		if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+			return "", nil
+		}
+		return util.MavenPath(value[1 : len(value)-1])
+	}
+	clientPath, err := artifactPath(mappings.Client)
+	if err != nil {
+		return "", "", err
+	}
+	serverPath, err := artifactPath(mappings.Server)
+	if err != nil {
+		return "", "", err
+	}
+	return clientPath, serverPath, nil
+}
+
+// Determines whether the given sha1 hash matches that of the library,
+// should it be known.
+func (l *Library) matches(hash string) bool {
+	if l.Sha1 != "" {
+		return hash == l.Sha1
+	}
+	if len(l.Checksums) == 0 {
+		return true
+	}
+	for _, checksum := range l.Checksums {
+		if hash == checksum {
+			return true
+		}
+	}
+	return false
+}
+
+// Downloads the given library, to the libraries directory within the given
+// destination - verifying it against its sha1 hash or checksums. Should
+// the library already exist, and match, it isn't downloaded again.
+func downloadLibrary(ctx context.Context, dest string, library *Library) error {
+	path := filepath.Join(dest, "libraries", filepath.FromSlash(library.Path))
+	if hash, err := util.HashFile(path); err == nil && library.matches(hash) {
+		return nil
+	}
+	fmt.Println("Downloading library " + library.Name + "...")
+
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, library.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/java-archive")
+
+	hash, err := util.DownloadFile(req, path, library.Sha1, 0)
+	if err != nil {
+		return err
+	}
+	if !library.matches(hash) {
+		os.Remove(path)
+		return fmt.Errorf("%w: %s was %s, expected one of %s", util.ErrChecksumMismatch, library.URL, hash, strings.Join(library.Checksums, ", "))
+	}
+	return nil
+}
+
+// Reads the given JSON file from within the zip.
+func readZipJson(zipFile *zip.Reader, name string, v interface{}) error {
+	file, err := util.GetFileInZip(zipFile, name)
+	if err != nil {
+		return err
+	}
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
@@ -0,0 +1,174 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package forge
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+func TestGetInstallerLibraries(t *testing.T) {
+	// Universal installer
+	{
+		installer := createZip(t, map[string]string{
+			"install_profile.json": `{"versionInfo": {"libraries": [
+				{"name": "net.minecraftforge:forge:1.12.2-14.23.5.2860", "url": "https://files.minecraftforge.net/maven/"},
+				{"name": "org.scala-lang:scala-library:2.11.1", "url": "https://files.minecraftforge.net/maven/", "serverreq": true, "clientreq": true, "checksums": ["abc", "def"]},
+				{"name": "lzma:lzma:0.0.1", "serverreq": true},
+				{"name": "com.mojang:realms:1.10.22", "clientreq": false}
+			]}}`,
+		})
+		libraries, err := GetInstallerLibraries(installer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(libraries) != 3 {
+			t.Fatalf("expected 3 libraries, got %d", len(libraries))
+		}
+		if libraries[0].URL != "https://files.minecraftforge.net/maven/org/scala-lang/scala-library/2.11.1/scala-library-2.11.1.jar" {
+			t.Errorf("unexpected url %s", libraries[0].URL)
+		}
+		if libraries[0].Sha1 != "" || !libraries[0].matches("def") || libraries[0].matches("ghi") {
+			t.Errorf("unexpected checksums %+v", libraries[0])
+		}
+		if libraries[1].URL != "https://libraries.minecraft.net/lzma/lzma/0.0.1/lzma-0.0.1.jar" || !libraries[1].ClientReq {
+			t.Errorf("unexpected library %+v", libraries[1])
+		}
+		if libraries[2].ClientReq || !libraries[2].ServerReq {
+			t.Errorf("realms should only be required by the server, got %+v", libraries[2])
+		}
+	}
+
+	// Modern installer
+	{
+		installer := createZip(t, map[string]string{
+			"install_profile.json": `{"json": "/version.json", "libraries": [
+				{"name": "net.minecraftforge:installertools:1.1.11", "downloads": {"artifact": {"path": "net/minecraftforge/installertools/1.1.11/installertools-1.1.11.jar", "url": "https://maven.minecraftforge.net/net/minecraftforge/installertools/1.1.11/installertools-1.1.11.jar", "sha1": "abc"}}},
+				{"name": "net.minecraftforge:forge:1.16.5-36.2.39", "downloads": {"artifact": {"path": "net/minecraftforge/forge/1.16.5-36.2.39/forge-1.16.5-36.2.39.jar", "url": ""}}}
+			]}`,
+			"version.json": `{"libraries": [
+				{"name": "net.minecraftforge:installertools:1.1.11", "downloads": {"artifact": {"path": "net/minecraftforge/installertools/1.1.11/installertools-1.1.11.jar", "url": "https://maven.minecraftforge.net/net/minecraftforge/installertools/1.1.11/installertools-1.1.11.jar", "sha1": "abc"}}},
+				{"name": "org.ow2.asm:asm:9.1", "downloads": {"artifact": {"path": "org/ow2/asm/asm/9.1/asm-9.1.jar", "url": "https://maven.minecraftforge.net/org/ow2/asm/asm/9.1/asm-9.1.jar", "sha1": "def"}}}
+			]}`,
+		})
+		libraries, err := GetInstallerLibraries(installer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(libraries) != 2 {
+			t.Fatalf("expected 2 libraries, got %d", len(libraries))
+		}
+		if libraries[0].Sha1 != "abc" || libraries[1].Path != "org/ow2/asm/asm/9.1/asm-9.1.jar" {
+			t.Errorf("unexpected libraries %+v, %+v", libraries[0], libraries[1])
+		}
+	}
+}
+
+func TestGetInstallerMappings(t *testing.T) {
+	installer := createZip(t, map[string]string{
+		"install_profile.json": `{"data": {
+			"MOJMAPS": {"client": "[net.minecraft:client:1.19.2-20220805.130853:mappings@txt]", "server": "[net.minecraft:server:1.19.2-20220805.130853:mappings@txt]"},
+			"SIDE": {"client": "client", "server": "server"}
+		}}`,
+	})
+	clientPath, serverPath, err := GetInstallerMappings(installer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientPath != "net/minecraft/client/1.19.2-20220805.130853/client-1.19.2-20220805.130853-mappings.txt" {
+		t.Errorf("unexpected client path %s", clientPath)
+	}
+	if serverPath != "net/minecraft/server/1.19.2-20220805.130853/server-1.19.2-20220805.130853-mappings.txt" {
+		t.Errorf("unexpected server path %s", serverPath)
+	}
+
+	// Before Minecraft 1.17
+	installer = createZip(t, map[string]string{
+		"install_profile.json": `{"data": {"SIDE": {"client": "client", "server": "server"}}}`,
+	})
+	if clientPath, serverPath, err := GetInstallerMappings(installer); err != nil || clientPath != "" || serverPath != "" {
+		t.Errorf("expected no mappings, got %q and %q (%v)", clientPath, serverPath, err)
+	}
+}
+
+func TestDownloadLibrary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "jar")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "forgelibraries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sum := sha1.Sum([]byte("jar"))
+	hash := hex.EncodeToString(sum[:])
+
+	// Matching one of its checksums
+	library := &Library{
+		Name:      "org.scala-lang:scala-library:2.11.1",
+		Path:      "org/scala-lang/scala-library/2.11.1/scala-library-2.11.1.jar",
+		URL:       server.URL + "/scala-library-2.11.1.jar",
+		Checksums: []string{"abc", hash},
+	}
+	if err := downloadLibrary(context.Background(), dir, library); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "libraries", filepath.FromSlash(library.Path))); err != nil {
+		t.Errorf("library wasn't downloaded: %v", err)
+	}
+
+	// Matching none of them
+	library = &Library{
+		Name:      "lzma:lzma:0.0.1",
+		Path:      "lzma/lzma/0.0.1/lzma-0.0.1.jar",
+		URL:       server.URL + "/lzma-0.0.1.jar",
+		Checksums: []string{"abc", "def"},
+	}
+	if err := downloadLibrary(context.Background(), dir, library); !errors.Is(err, util.ErrChecksumMismatch) {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "libraries", filepath.FromSlash(library.Path))); !os.IsNotExist(err) {
+		t.Errorf("mismatched library should have been removed: %v", err)
+	}
+}
+
+func createZip(t *testing.T, files map[string]string) *zip.Reader {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for name, contents := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
@@ -0,0 +1,83 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package forge
+
+import (
+	"context"
+	"net/url"
+	"os"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+// Gets the Maven artifact (and its version) of the given version of
+// NeoForge. NeoForge for Minecraft 1.20.1 was published as a fork of
+// Minecraft Forge, using its version scheme (MC-Forge) - later versions
+// are published as neoforge, with versions such as 20.4.237.
+func neoForgeArtifact(mcVersion *minecraft.Version, neoForgeVersion string) (string, string) {
+	if mcVersion.Major == 1 && mcVersion.Minor == 20 && mcVersion.Revision <= 1 {
+		return "forge", mcVersion.String() + "-" + neoForgeVersion
+	}
+	return "neoforge", neoForgeVersion
+}
+
+// NeoForgeVersionName gets the name of the launcher version for the given
+// version of NeoForge, such as neoforge-20.4.237.
+func NeoForgeVersionName(mcVersion *minecraft.Version, neoForgeVersion string) string {
+	artifact, _ := neoForgeArtifact(mcVersion, neoForgeVersion)
+	if artifact == "forge" {
+		return mcVersion.String() + "-forge-" + neoForgeVersion
+	}
+	return "neoforge-" + neoForgeVersion
+}
+
+// NeoForgeInstallerURL gets the URL of the NeoForge installer for the
+// given version.
+func (i *Installer) NeoForgeInstallerURL(mcVersion *minecraft.Version, neoForgeVersion string) (*url.URL, error) {
+	artifact, version := neoForgeArtifact(mcVersion, neoForgeVersion)
+	return i.NeoForgeMavenRoot.Parse("net/neoforged/" + artifact + "/" + version + "/" + artifact + "-" + version + "-installer.jar")
+}
+
+// DownloadNeoForgeInstaller downloads the NeoForge installer for the given
+// version, to a temporary file.
+// The temporary file should be removed after usage.
+func (i *Installer) DownloadNeoForgeInstaller(ctx context.Context, mcVersion *minecraft.Version, neoForgeVersion string) (*os.File, error) {
+	u, err := i.NeoForgeInstallerURL(mcVersion, neoForgeVersion)
+	if err != nil {
+		return nil, err
+	}
+	return downloadInstaller(ctx, u)
+}
+
+// Installs NeoForge to the given destination, for the given target.
+// If the target is Server, the destination will be the root directory of
+// the server; if the target is Client, the destination will be the
+// launcher's root directory.
+func (i *Installer) InstallNeoForge(target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, neoForgeVersion string) error {
+	return i.InstallNeoForgeContext(context.Background(), target, dest, mcVersion, neoForgeVersion)
+}
+
+// See InstallNeoForge
+// Should the context be cancelled, any in-progress download will be
+// aborted and the NeoForge installer process killed.
+func (i *Installer) InstallNeoForgeContext(ctx context.Context, target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, neoForgeVersion string) error {
+	installerURL, err := i.NeoForgeInstallerURL(mcVersion, neoForgeVersion)
+	if err != nil {
+		return err
+	}
+	artifact, version := neoForgeArtifact(mcVersion, neoForgeVersion)
+	versionName := NeoForgeVersionName(mcVersion, neoForgeVersion)
+
+	// NeoForge is only available for Minecraft 1.20.1 and above, so servers
+	// are always launched with the installer's run scripts
+	return i.installModern(ctx, target, dest, &modernBuild{
+		name:           "NeoForge",
+		version:        version,
+		installerURL:   installerURL,
+		serverCheck:    "libraries/net/neoforged/" + artifact + "/" + version + "/unix_args.txt",
+		clientCheck:    "versions/" + versionName + "/" + versionName + ".json",
+		installsClient: true,
+	})
+}
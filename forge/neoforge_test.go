@@ -0,0 +1,45 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package forge
+
+import (
+	"testing"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+func TestNeoForge(t *testing.T) {
+	installer := NewInstaller()
+
+	// 1.20.1, published as a fork of Forge
+	{
+		mcVersion, _ := minecraft.ParseVersion("1.20.1")
+		if name := NeoForgeVersionName(mcVersion, "47.1.106"); name != "1.20.1-forge-47.1.106" {
+			t.Errorf("unexpected version name %s", name)
+		}
+		u, err := installer.NeoForgeInstallerURL(mcVersion, "47.1.106")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != "https://maven.neoforged.net/releases/net/neoforged/forge/1.20.1-47.1.106/forge-1.20.1-47.1.106-installer.jar" {
+			t.Errorf("unexpected installer url %s", u)
+		}
+	}
+
+	// 1.20.4
+	{
+		mcVersion, _ := minecraft.ParseVersion("1.20.4")
+		if name := NeoForgeVersionName(mcVersion, "20.4.237"); name != "neoforge-20.4.237" {
+			t.Errorf("unexpected version name %s", name)
+		}
+		u, err := installer.NeoForgeInstallerURL(mcVersion, "20.4.237")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != "https://maven.neoforged.net/releases/net/neoforged/neoforge/20.4.237/neoforge-20.4.237-installer.jar" {
+			t.Errorf("unexpected installer url %s", u)
+		}
+	}
+}
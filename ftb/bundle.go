@@ -0,0 +1,724 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/fabric"
+	"github.com/jamiemansfield/mcinstall/forge"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/minecraft/launcher"
+	"github.com/jamiemansfield/mcinstall/minecraft/manifest"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	bundleManifest     = "bundle.json"
+	bundleResourcesDir = "resources/"
+)
+
+var (
+	ErrInvalidBundle = errors.New("ftb: not a valid offline bundle")
+)
+
+// Bundle is an offline bundle, containing everything needed to install a
+// pack version without network access: the pack's metadata, every file
+// of the pack, the Forge installer and its libraries, and the vanilla
+// client and server jars (along with Mojang's mappings).
+//
+// A Bundle is an http.RoundTripper, serving the resources it contains in
+// place of the network.
+type Bundle struct {
+	Pack    *modpacksch.Pack
+	Version *modpacksch.PackVersion
+
+	resources map[string]*bundleResource
+	files     map[string]*zip.File
+	closer    io.Closer
+}
+
+// bundle.json
+type bundleFile struct {
+	Pack      *modpacksch.Pack           `json:"pack"`
+	Version   *modpacksch.PackVersion    `json:"version"`
+	Resources map[string]*bundleResource `json:"resources"`
+}
+
+// A resource within a bundle, keyed by the URL it was downloaded from.
+type bundleResource struct {
+	Sha1 string `json:"sha1"`
+	Size int64  `json:"size"`
+
+	// Where the resource should be placed ahead of installing, relative to
+	// the launcher directory for client installs, or the destination for
+	// server installs. The Forge installer will find these in place, rather
+	// than downloading them.
+	ClientPaths []string `json:"clientPaths,omitempty"`
+	ServerPaths []string `json:"serverPaths,omitempty"`
+}
+
+// OpenBundle opens the offline bundle at the given path.
+// The bundle should be closed after usage.
+func OpenBundle(path string) (*Bundle, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var contents bundleFile
+	if err := readBundleJson(&r.Reader, &contents); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if contents.Pack == nil || contents.Version == nil {
+		r.Close()
+		return nil, ErrInvalidBundle
+	}
+
+	bundle := &Bundle{
+		Pack:      contents.Pack,
+		Version:   contents.Version,
+		resources: contents.Resources,
+		files:     map[string]*zip.File{},
+		closer:    r,
+	}
+	for url, resource := range contents.Resources {
+		file, err := util.GetFileInZip(&r.Reader, bundleResourceName(url))
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		bundle.files[url] = file
+
+		// Resources can't be placed outside of the install
+		for _, path := range append(resource.ClientPaths, resource.ServerPaths...) {
+			if _, err := resolveResourcePath(".", path); err != nil {
+				r.Close()
+				return nil, err
+			}
+		}
+	}
+	return bundle, nil
+}
+
+// Gets where the given resource path should be placed, within the given
+// directory - rejecting any path outside of it.
+func resolveResourcePath(dir string, path string) (string, error) {
+	resolved, _, err := resolveInstallPath(dir, path)
+	if errors.Is(err, ErrPathOutsideInstall) {
+		return "", fmt.Errorf("%w: resource path %s is outside of the install", ErrInvalidBundle, path)
+	}
+	return resolved, err
+}
+
+func readBundleJson(r *zip.Reader, v interface{}) error {
+	file, err := util.GetFileInZip(r, bundleManifest)
+	if err != nil {
+		return ErrInvalidBundle
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return json.NewDecoder(reader).Decode(v)
+}
+
+// Close closes the bundle.
+func (b *Bundle) Close() error {
+	return b.closer.Close()
+}
+
+// RoundTrip serves the request from the bundle. Should the bundle not
+// contain the requested resource, a 404 response is given.
+func (b *Bundle) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Request:    req,
+	}
+
+	file, ok := b.files[req.URL.String()]
+	if !ok || req.Method != http.MethodGet {
+		resp.StatusCode = http.StatusNotFound
+		resp.Status = "404 not found in the offline bundle"
+		resp.Body = ioutil.NopCloser(strings.NewReader(""))
+		return resp, nil
+	}
+
+	body, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	resp.StatusCode = http.StatusOK
+	resp.Status = "200 OK"
+	resp.ContentLength = int64(file.UncompressedSize64)
+	resp.Body = body
+	return resp, nil
+}
+
+// Places the bundle's resources needed by the given install target, that
+// don't already exist.
+func (b *Bundle) placeResources(installTarget minecraft.InstallTarget, dir string) error {
+	for url, resource := range b.resources {
+		paths := resource.ServerPaths
+		if installTarget == minecraft.Client {
+			paths = resource.ClientPaths
+		}
+
+		for _, path := range paths {
+			path, err := resolveResourcePath(dir, path)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); err == nil {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+				return err
+			}
+			if err := util.CopyZipFileToDisk(b.files[url], path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Installs the pack version contained within the given bundle to the
+// destination, with the appropriate files for that install target.
+func (i *Installer) InstallBundle(installTarget minecraft.InstallTarget, dest string, bundle *Bundle) error {
+	return i.InstallBundleContext(context.Background(), installTarget, dest, bundle)
+}
+
+// See InstallBundle
+// While installing, the install's requests are served from the bundle -
+// any other installs taking place at the same time are unaffected.
+func (i *Installer) InstallBundleContext(ctx context.Context, installTarget minecraft.InstallTarget, dest string, bundle *Bundle) error {
+	ctx = util.WithTransport(ctx, bundle)
+
+	// Put everything the Forge installer would download in place
+	dir := dest
+	if installTarget == minecraft.Client {
+		dir = launcher.GetLauncherDir()
+	}
+	if err := bundle.placeResources(installTarget, dir); err != nil {
+		return err
+	}
+
+	return i.InstallPackVersionContext(ctx, installTarget, dest, bundle.Pack, bundle.Version)
+}
+
+// Exports the given pack version to an offline bundle at the given path,
+// which can then be installed using InstallBundle.
+func (i *Installer) ExportBundle(path string, pack *modpacksch.Pack, version *modpacksch.PackVersion) error {
+	return i.ExportBundleContext(context.Background(), path, pack, version)
+}
+
+// See ExportBundle
+// Should the context be cancelled, or the export fail, the partial bundle
+// is removed.
+func (i *Installer) ExportBundleContext(ctx context.Context, path string, pack *modpacksch.Pack, version *modpacksch.PackVersion) error {
+	tmp, err := ioutil.TempDir("", "mcinstall-bundle")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	exporter := &bundleExporter{
+		ctx:       ctx,
+		tmp:       tmp,
+		zip:       zip.NewWriter(f),
+		resources: map[string]*bundleResource{},
+	}
+	err = i.exportBundle(exporter, pack, version)
+	if err == nil {
+		err = exporter.finish(pack, version)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+func (i *Installer) exportBundle(exporter *bundleExporter, pack *modpacksch.Pack, version *modpacksch.PackVersion) error {
+	mcVersion, err := getGameVersion(version.Targets)
+	if err != nil {
+		return err
+	}
+
+	// The pack's files, and its icon
+	fmt.Printf("Exporting %s v%s...\n", pack.Name, version.Name)
+	for _, file := range version.Files {
+		if file.URL == "" {
+			continue
+		}
+		if err := exporter.add(file.URL, file.Sha1, int64(file.Size), nil, nil); err != nil {
+			return err
+		}
+	}
+	if icon := pack.GetIcon(); icon != nil && icon.URL != "" {
+		if err := exporter.add(icon.URL, "", 0, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	// Vanilla Minecraft
+	fmt.Printf("Exporting Minecraft %s...\n", mcVersion)
+	if err := exportMinecraft(exporter, mcVersion); err != nil {
+		return err
+	}
+
+	// Mod loaders
+	for _, target := range version.Targets {
+		if target.Type != "modloader" {
+			continue
+		}
+
+		// Minecraft Forge
+		if target.Name == "forge" {
+			fmt.Printf("Exporting Minecraft Forge %s...\n", target.Version)
+			if err := i.exportForge(exporter, mcVersion, target.Version); err != nil {
+				return err
+			}
+		}
+
+		// NeoForge
+		if target.Name == "neoforge" {
+			fmt.Printf("Exporting NeoForge %s...\n", target.Version)
+			if err := i.exportNeoForge(exporter, mcVersion, target.Version); err != nil {
+				return err
+			}
+		}
+
+		// Fabric Loader
+		if target.Name == "fabric" {
+			fmt.Printf("Exporting Fabric Loader %s...\n", target.Version)
+			if err := exportFabric(exporter, i.FabricInstaller, mcVersion, target.Version); err != nil {
+				return err
+			}
+		}
+
+		// Quilt Loader
+		if target.Name == "quilt" {
+			fmt.Printf("Exporting Quilt Loader %s...\n", target.Version)
+			if err := exportFabric(exporter, i.QuiltInstaller, mcVersion, target.Version); err != nil {
+				return err
+			}
+		}
+
+		// LiteLoader
+		if target.Name == "liteloader" {
+			fmt.Printf("Exporting LiteLoader %s...\n", target.Version)
+			if err := i.exportLiteLoader(exporter, mcVersion, target.Version); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Exports the vanilla client and server, along with the client's
+// libraries and Mojang's mappings.
+func exportMinecraft(exporter *bundleExporter, mcVersion *minecraft.Version) error {
+	name := mcVersion.String()
+
+	versions, err := manifest.GetVersionManifestContext(exporter.ctx, nil)
+	if err != nil {
+		return err
+	}
+	versionInfo := versions.FindVersion(name)
+	if versionInfo == nil {
+		return launcher.ErrVersionDoesntExist
+	}
+	version, err := versionInfo.GetFullContext(exporter.ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := exporter.add(manifest.VersionManifestURL, "", 0, nil, nil); err != nil {
+		return err
+	}
+	if err := exporter.add(versionInfo.URL, "", 0, []string{"versions/" + name + "/" + name + ".json"}, nil); err != nil {
+		return err
+	}
+	if client := version.Downloads.Client; client != nil {
+		if err := exporter.add(client.URL, client.Sha1, int64(client.Size), []string{"versions/" + name + "/" + name + ".jar"}, nil); err != nil {
+			return err
+		}
+	}
+	if server := version.Downloads.Server; server != nil {
+		// Minecraft 1.17 and above keep the server jar with the libraries
+		serverPath := "minecraft_server." + name + ".jar"
+		if mcVersion.Major >= 1 && mcVersion.Minor >= 17 {
+			serverPath = "libraries/net/minecraft/server/" + name + "/server-" + name + ".jar"
+		}
+		if err := exporter.add(server.URL, server.Sha1, int64(server.Size), nil, []string{serverPath}); err != nil {
+			return err
+		}
+	}
+
+	// Mojang's mappings, downloaded by the processors of the Forge
+	// installer for Minecraft 1.17 and above
+	if mappings := version.Downloads.ClientMappings; mappings != nil {
+		if err := exporter.add(mappings.URL, mappings.Sha1, int64(mappings.Size), nil, nil); err != nil {
+			return err
+		}
+		exporter.clientMappings = mappings.URL
+	}
+	if mappings := version.Downloads.ServerMappings; mappings != nil {
+		if err := exporter.add(mappings.URL, mappings.Sha1, int64(mappings.Size), nil, nil); err != nil {
+			return err
+		}
+		exporter.serverMappings = mappings.URL
+	}
+
+	for _, library := range version.Libraries {
+		downloads := []*manifest.LibraryDownload{library.Downloads.Artifact}
+		for _, classifier := range library.Downloads.Classifiers {
+			downloads = append(downloads, classifier)
+		}
+
+		for _, download := range downloads {
+			if download == nil || download.URL == "" {
+				continue
+			}
+			if err := exporter.add(download.URL, download.Sha1, int64(download.Size), []string{"libraries/" + download.Path}, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Exports the Forge installer, along with every library it will download.
+func (i *Installer) exportForge(exporter *bundleExporter, mcVersion *minecraft.Version, forgeVersion string) error {
+	if forge.IsLegacy(mcVersion) {
+		return i.exportLegacyForge(exporter, mcVersion, forgeVersion)
+	}
+	version := mcVersion.String() + "-" + forgeVersion
+
+	installerURL, err := i.ForgeInstaller.InstallerURL(version)
+	if err != nil {
+		return err
+	}
+	installerJar, err := i.ForgeInstaller.DownloadInstaller(exporter.ctx, version)
+	if err != nil {
+		return err
+	}
+	return exportForgeInstaller(exporter, installerURL, installerJar)
+}
+
+// Exports the zips of Minecraft Forge merged into the client and server
+// jars, along with the libraries FML would download.
+func (i *Installer) exportLegacyForge(exporter *bundleExporter, mcVersion *minecraft.Version, forgeVersion string) error {
+	seen := map[string]bool{}
+	for _, target := range []minecraft.InstallTarget{minecraft.Client, minecraft.Server} {
+		u, err := i.ForgeInstaller.LegacyURL(target, mcVersion, forgeVersion)
+		if err != nil {
+			return err
+		}
+		if seen[u.String()] {
+			continue
+		}
+		seen[u.String()] = true
+
+		// Without its sha1 hash in the bundle, the zip won't be verified
+		// again on install
+		if err := exporter.add(u.String(), "", 0, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	libraries, err := i.ForgeInstaller.FMLLibraries(mcVersion)
+	if err != nil {
+		return err
+	}
+	for _, library := range libraries {
+		if err := exporter.add(library.URL, library.Sha1, 0, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Exports the NeoForge installer, along with every library it will
+// download.
+func (i *Installer) exportNeoForge(exporter *bundleExporter, mcVersion *minecraft.Version, neoForgeVersion string) error {
+	installerURL, err := i.ForgeInstaller.NeoForgeInstallerURL(mcVersion, neoForgeVersion)
+	if err != nil {
+		return err
+	}
+	installerJar, err := i.ForgeInstaller.DownloadNeoForgeInstaller(exporter.ctx, mcVersion, neoForgeVersion)
+	if err != nil {
+		return err
+	}
+	return exportForgeInstaller(exporter, installerURL, installerJar)
+}
+
+// Exports the given Forge (or NeoForge) installer, downloaded from the
+// given URL, along with every library it will download. The installer is
+// removed once exported.
+func exportForgeInstaller(exporter *bundleExporter, installerURL *url.URL, installerJar *os.File) error {
+	defer func() {
+		installerJar.Close()
+		os.Remove(installerJar.Name())
+	}()
+	installerInfo, err := installerJar.Stat()
+	if err != nil {
+		return err
+	}
+	reader, err := zip.NewReader(installerJar, installerInfo.Size())
+	if err != nil {
+		return err
+	}
+	libraries, err := forge.GetInstallerLibraries(reader)
+	if err != nil {
+		return err
+	}
+
+	// The installer has been verified against its published sha1 hash, so
+	// the hash itself needn't be bundled.
+	if err := exporter.store(installerURL.String(), installerJar.Name(), &bundleResource{}); err != nil {
+		return err
+	}
+
+	// Put the mappings where the installer's processors expect them
+	clientMappingsPath, serverMappingsPath, err := forge.GetInstallerMappings(reader)
+	if err != nil {
+		return err
+	}
+	if clientMappingsPath != "" && exporter.clientMappings != "" {
+		if err := exporter.add(exporter.clientMappings, "", 0, []string{"libraries/" + clientMappingsPath}, nil); err != nil {
+			return err
+		}
+	}
+	if serverMappingsPath != "" && exporter.serverMappings != "" {
+		if err := exporter.add(exporter.serverMappings, "", 0, nil, []string{"libraries/" + serverMappingsPath}); err != nil {
+			return err
+		}
+	}
+
+	for _, library := range libraries {
+		var clientPaths, serverPaths []string
+		if library.ClientReq {
+			clientPaths = []string{"libraries/" + library.Path}
+		}
+		if library.ServerReq {
+			serverPaths = []string{"libraries/" + library.Path}
+		}
+		if err := exporter.add(library.URL, library.Sha1, 0, clientPaths, serverPaths); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Exports LiteLoader's list of versions, along with the given build of
+// LiteLoader and its libraries.
+func (i *Installer) exportLiteLoader(exporter *bundleExporter, mcVersion *minecraft.Version, version string) error {
+	build, err := i.LiteLoaderInstaller.GetBuild(exporter.ctx, mcVersion, version)
+	if err != nil {
+		return err
+	}
+
+	if err := exporter.add(i.LiteLoaderInstaller.VersionsURL.String(), "", 0, nil, nil); err != nil {
+		return err
+	}
+	if err := exporter.add(build.URL, "", 0, nil, nil); err != nil {
+		return err
+	}
+	for _, library := range build.Libraries {
+		u, err := library.DownloadURL()
+		if err != nil {
+			return err
+		}
+		if err := exporter.add(u, "", 0, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// An installer of Fabric Loader, or a loader installed in the same way
+type fabricInstaller interface {
+	ProfileURL(target minecraft.InstallTarget, mcVersion *minecraft.Version, loaderVersion string) (*url.URL, error)
+	GetProfile(ctx context.Context, target minecraft.InstallTarget, mcVersion *minecraft.Version, loaderVersion string) (*fabric.Profile, error)
+}
+
+// Exports the client and server profiles of Fabric Loader (or a loader
+// installed in the same way), along with their libraries.
+func exportFabric(exporter *bundleExporter, installer fabricInstaller, mcVersion *minecraft.Version, loaderVersion string) error {
+	for _, target := range []minecraft.InstallTarget{minecraft.Client, minecraft.Server} {
+		profileURL, err := installer.ProfileURL(target, mcVersion, loaderVersion)
+		if err != nil {
+			return err
+		}
+		profile, err := installer.GetProfile(exporter.ctx, target, mcVersion, loaderVersion)
+		if err != nil {
+			return err
+		}
+		if err := exporter.add(profileURL.String(), "", 0, nil, nil); err != nil {
+			return err
+		}
+
+		for _, library := range profile.Libraries {
+			u, err := library.DownloadURL()
+			if err != nil {
+				return err
+			}
+			if err := exporter.add(u, library.Sha1, library.Size, nil, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Writes the resources of an offline bundle.
+type bundleExporter struct {
+	ctx       context.Context
+	tmp       string
+	zip       *zip.Writer
+	resources map[string]*bundleResource
+
+	// The URLs of Mojang's client and server mappings, should the version
+	// of Minecraft have them
+	clientMappings string
+	serverMappings string
+}
+
+// Downloads the given resource, and adds it to the bundle.
+func (e *bundleExporter) add(url string, sha1 string, size int64, clientPaths []string, serverPaths []string) error {
+	if resource, ok := e.resources[url]; ok {
+		resource.ClientPaths = appendPaths(resource.ClientPaths, clientPaths)
+		resource.ServerPaths = appendPaths(resource.ServerPaths, serverPaths)
+		return nil
+	}
+
+	req, err := util.NewRequestWithContext(e.ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "*")
+
+	path := filepath.Join(e.tmp, bundleResourceName(url))
+	if _, err := util.DownloadFile(req, path, sha1, size); err != nil {
+		return err
+	}
+	return e.store(url, path, &bundleResource{
+		ClientPaths: clientPaths,
+		ServerPaths: serverPaths,
+	})
+}
+
+// Adds the file at the given path to the bundle, as the given resource.
+// The file is removed once added.
+func (e *bundleExporter) store(url string, path string, resource *bundleResource) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(path)
+	}()
+
+	// Archives are already compressed
+	method := zip.Deflate
+	if ext := strings.ToLower(filepath.Ext(strings.SplitN(url, "?", 2)[0])); ext == ".jar" || ext == ".zip" {
+		method = zip.Store
+	}
+	w, err := e.zip.CreateHeader(&zip.FileHeader{
+		Name:   bundleResourceName(url),
+		Method: method,
+	})
+	if err != nil {
+		return err
+	}
+
+	hasher := sha1.New()
+	size, err := io.Copy(io.MultiWriter(w, hasher), f)
+	if err != nil {
+		return err
+	}
+	resource.Sha1 = hex.EncodeToString(hasher.Sum(nil))
+	resource.Size = size
+	e.resources[url] = resource
+	return nil
+}
+
+// Writes bundle.json, and finishes the bundle.
+func (e *bundleExporter) finish(pack *modpacksch.Pack, version *modpacksch.PackVersion) error {
+	w, err := e.zip.Create(bundleManifest)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	if err := encoder.Encode(&bundleFile{
+		Pack:      pack,
+		Version:   version,
+		Resources: e.resources,
+	}); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// Gets the name of the entry within a bundle, for the resource from the
+// given URL.
+func bundleResourceName(url string) string {
+	hash := sha1.Sum([]byte(url))
+	return bundleResourcesDir + hex.EncodeToString(hash[:])
+}
+
+func appendPaths(paths []string, more []string) []string {
+	for _, path := range more {
+		found := false
+		for _, existing := range paths {
+			if existing == path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
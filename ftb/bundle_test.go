@@ -0,0 +1,304 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/minecraft/manifest"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+func TestBundle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "ftbbundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Export a bundle, with a file and a library
+	path := filepath.Join(dir, "pack.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := &bundleExporter{
+		ctx:       context.Background(),
+		tmp:       dir,
+		zip:       zip.NewWriter(f),
+		resources: map[string]*bundleResource{},
+	}
+	if err := exporter.add(server.URL+"/mods/example.jar", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", 5, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.add(server.URL+"/libraries/example.jar", "", 0, []string{"libraries/example.jar"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	pack := &modpacksch.Pack{ID: 1, Name: "Example"}
+	version := &modpacksch.PackVersion{ID: 10, Name: "1.0.0"}
+	if err := exporter.finish(pack, version); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := OpenBundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundle.Close()
+	if bundle.Pack.ID != 1 || bundle.Version.ID != 10 {
+		t.Errorf("expected pack 1 version 10, got %d and %d", bundle.Pack.ID, bundle.Version.ID)
+	}
+
+	// Resources are served from the bundle, in place of the network
+	client := &http.Client{Transport: bundle}
+	server.Close()
+	resp, err := client.Get(server.URL + "/mods/example.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || string(contents) != "hello" {
+		t.Errorf("expected hello, got %d %q", resp.StatusCode, contents)
+	}
+	resp, err = client.Get(server.URL + "/missing.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a missing resource to 404, got %d", resp.StatusCode)
+	}
+
+	// As are the requests of an install, made with the bundle's context
+	req, err := util.NewRequestWithContext(util.WithTransport(context.Background(), bundle), http.MethodGet, server.URL+"/mods/example.jar", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = util.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the bundle to serve the request, got %d", resp.StatusCode)
+	}
+
+	// Libraries are placed for the client, but not the server
+	launcherDir := filepath.Join(dir, "launcher")
+	if err := bundle.placeResources(minecraft.Client, launcherDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(launcherDir, "libraries", "example.jar")); err != nil {
+		t.Errorf("library wasn't placed: %v", err)
+	}
+	serverDir := filepath.Join(dir, "server")
+	if err := bundle.placeResources(minecraft.Server, serverDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(serverDir); !os.IsNotExist(err) {
+		t.Errorf("nothing should be placed for the server")
+	}
+}
+
+func TestBundleOutsideInstall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "ftbbundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A bundle that would place a library outside of the launcher
+	path := filepath.Join(dir, "pack.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := &bundleExporter{
+		ctx:       context.Background(),
+		tmp:       dir,
+		zip:       zip.NewWriter(f),
+		resources: map[string]*bundleResource{},
+	}
+	if err := exporter.add(server.URL+"/libraries/example.jar", "", 0, []string{"libraries/../../evil.jar"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.finish(&modpacksch.Pack{ID: 1}, &modpacksch.PackVersion{ID: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenBundle(path); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("bundle should have been rejected, got %v", err)
+	}
+
+	// Nor will such a resource be placed
+	bundle := &Bundle{
+		resources: map[string]*bundleResource{
+			"https://example.com/evil.jar": {ClientPaths: []string{"/evil.jar"}},
+		},
+	}
+	if err := bundle.placeResources(minecraft.Client, filepath.Join(dir, "launcher")); !errors.Is(err, ErrInvalidBundle) {
+		t.Errorf("resource should have been rejected, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.jar")); !os.IsNotExist(err) {
+		t.Errorf("resource shouldn't have been placed outside of the launcher")
+	}
+}
+
+// Serves the given URLs, in place of the network
+type testTransport map[string]string
+
+func (t testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, ok := t[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader("")), Request: req}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func TestBundleMappings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ftbbundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const (
+		clientMappingsURL = "https://example.com/client.txt"
+		serverMappingsURL = "https://example.com/server.txt"
+	)
+	transport := testTransport{
+		manifest.VersionManifestURL: `{"versions": [{"id": "1.19.2", "url": "https://example.com/1.19.2.json"}]}`,
+		"https://example.com/1.19.2.json": `{"id": "1.19.2", "downloads": {
+			"client": {"url": "https://example.com/client.jar"},
+			"client_mappings": {"url": "` + clientMappingsURL + `"},
+			"server": {"url": "https://example.com/server.jar"},
+			"server_mappings": {"url": "` + serverMappingsURL + `"}
+		}}`,
+		"https://example.com/client.jar": "client",
+		"https://example.com/server.jar": "server",
+		clientMappingsURL:                "client mappings",
+		serverMappingsURL:                "server mappings",
+	}
+
+	// The Forge installer, whose processors use the mappings
+	installerPath := filepath.Join(dir, "installer.jar")
+	installerFile, err := os.Create(installerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(installerFile)
+	w, err := zw.Create("install_profile.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(w, `{"data": {"MOJMAPS": {
+		"client": "[net.minecraft:client:1.19.2-20220805.130853:mappings@txt]",
+		"server": "[net.minecraft:server:1.19.2-20220805.130853:mappings@txt]"
+	}}}`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := installerFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+	installerJar, err := os.Open(installerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "pack.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter := &bundleExporter{
+		ctx:       util.WithTransport(context.Background(), transport),
+		tmp:       dir,
+		zip:       zip.NewWriter(f),
+		resources: map[string]*bundleResource{},
+	}
+	mcVersion, _ := minecraft.ParseVersion("1.19.2")
+	if err := exportMinecraft(exporter, mcVersion); err != nil {
+		t.Fatal(err)
+	}
+	installerURL, _ := url.Parse("https://example.com/forge-installer.jar")
+	if err := exportForgeInstaller(exporter, installerURL, installerJar); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.finish(&modpacksch.Pack{ID: 1}, &modpacksch.PackVersion{ID: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := OpenBundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundle.Close()
+
+	// The mappings are served from the bundle
+	client := &http.Client{Transport: bundle}
+	for u, expected := range map[string]string{clientMappingsURL: "client mappings", serverMappingsURL: "server mappings"} {
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(contents) != expected {
+			t.Errorf("expected %s, got %d %q", expected, resp.StatusCode, contents)
+		}
+	}
+
+	// And placed where the installer's processors expect them
+	launcherDir := filepath.Join(dir, "launcher")
+	if err := bundle.placeResources(minecraft.Client, launcherDir); err != nil {
+		t.Fatal(err)
+	}
+	assertTestFile(t, launcherDir, "libraries/net/minecraft/client/1.19.2-20220805.130853/client-1.19.2-20220805.130853-mappings.txt", "client mappings")
+	serverDir := filepath.Join(dir, "server")
+	if err := bundle.placeResources(minecraft.Server, serverDir); err != nil {
+		t.Fatal(err)
+	}
+	assertTestFile(t, serverDir, "libraries/net/minecraft/server/1.19.2-20220805.130853/server-1.19.2-20220805.130853-mappings.txt", "server mappings")
+}
@@ -0,0 +1,116 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+// UpdateCheck describes whether an update is available for an installed
+// pack.
+type UpdateCheck struct {
+	Pack *modpacksch.Pack `json:"-"`
+
+	// The installed version, and the newest version of the pack. The
+	// installed version is nil should it no longer be listed by the pack.
+	Current *modpacksch.VersionInfo `json:"current"`
+	Latest  *modpacksch.VersionInfo `json:"latest"`
+
+	UpdateAvailable bool `json:"updateAvailable"`
+
+	// The changelog of the newest version, should an update be available
+	Changelog string `json:"changelog,omitempty"`
+}
+
+type changelogResponse struct {
+	Content string `json:"content"`
+
+	// Set should the request fail
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// CheckForUpdate checks whether a newer version of the pack installed at
+// the given destination is available. The newest version is found using
+// the given query, as with ResolveVersion - for example LatestRelease.
+func (i *Installer) CheckForUpdate(ctx context.Context, client *modpacksch.Client, dest string, query string) (*UpdateCheck, error) {
+	settings, err := i.GetInstallSettings(dest)
+	if err != nil {
+		return nil, err
+	}
+	pack, err := client.Packs.GetPack(settings.Pack)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := checkForUpdate(settings, pack, query)
+	if err != nil {
+		return nil, err
+	}
+	if check.UpdateAvailable {
+		check.Changelog, err = GetChangelog(ctx, nil, pack.ID, check.Latest.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return check, nil
+}
+
+func checkForUpdate(settings *InstallSettings, pack *modpacksch.Pack, query string) (*UpdateCheck, error) {
+	latest, err := ResolveVersion(pack, query)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &UpdateCheck{
+		Pack:   pack,
+		Latest: latest,
+	}
+	for _, version := range pack.Versions {
+		if version.ID == settings.Version {
+			check.Current = version
+		}
+	}
+	check.UpdateAvailable = latest.ID > settings.Version
+	return check, nil
+}
+
+// GetChangelog gets the changelog of the given version of a pack.
+func GetChangelog(ctx context.Context, httpClient *http.Client, pack int, version int) (string, error) {
+	if httpClient == nil {
+		httpClient = util.HTTPClient
+	}
+
+	// Create the request
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, apiURL+"modpack/"+strconv.Itoa(pack)+"/"+strconv.Itoa(version)+"/changelog", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// Make the request
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// Get the changelog
+	var response changelogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", err
+	}
+	if response.Status == "error" {
+		return "", fmt.Errorf("ftb: failed to get changelog: %s", response.Message)
+	}
+	return response.Content, nil
+}
@@ -0,0 +1,70 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+)
+
+func TestCheckForUpdate(t *testing.T) {
+	pack := &modpacksch.Pack{
+		ID: 1,
+		Versions: []*modpacksch.VersionInfo{
+			{ID: 10, Name: "1.0.0", Type: "Release"},
+			{ID: 11, Name: "1.1.0", Type: "Beta"},
+		},
+	}
+
+	check, err := checkForUpdate(&InstallSettings{Pack: 1, Version: 10}, pack, LatestRelease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.UpdateAvailable {
+		t.Error("no release should be available")
+	}
+
+	check, err = checkForUpdate(&InstallSettings{Pack: 1, Version: 10}, pack, LatestBeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !check.UpdateAvailable || check.Current.ID != 10 || check.Latest.ID != 11 {
+		t.Errorf("expected an update from 10 to 11, got %+v", check)
+	}
+}
+
+func TestGetChangelog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/modpack/1/11/changelog" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"status": "error", "message": "not found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"content": "Updated mods", "status": "success"}`)
+	}))
+	defer server.Close()
+
+	defer func(original string) {
+		apiURL = original
+	}(apiURL)
+	apiURL = server.URL + "/"
+
+	changelog, err := GetChangelog(context.Background(), nil, 1, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changelog != "Updated mods" {
+		t.Errorf("unexpected changelog %q", changelog)
+	}
+
+	if _, err := GetChangelog(context.Background(), nil, 1, 12); err == nil {
+		t.Error("expected getting the changelog to fail")
+	}
+}
@@ -0,0 +1,236 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+// FileChange describes how a file differs between two pack versions.
+type FileChange int
+
+const (
+	// The file is new to the pack.
+	FileAdded FileChange = iota
+
+	// The file is no longer a part of the pack.
+	FileRemoved
+
+	// The file's sha1 hash has changed.
+	FileChanged
+)
+
+func (c FileChange) String() string {
+	switch c {
+	case FileAdded:
+		return "added"
+	case FileRemoved:
+		return "removed"
+	case FileChanged:
+		return "changed"
+	default:
+		return "unknown"
+	}
+}
+
+func (c FileChange) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// FileDiff describes a file that differs between two pack versions.
+type FileDiff struct {
+	// The path of the file within the pack, for example
+	// ./mods/example.jar
+	Path   string     `json:"path"`
+	Change FileChange `json:"change"`
+
+	// The sha1 hashes of the file in each version, if any.
+	FromSha1 string `json:"fromSha1,omitempty"`
+	ToSha1   string `json:"toSha1,omitempty"`
+
+	// Whether the file is a config, rather than a mod or other resource.
+	Config bool `json:"config"`
+}
+
+// TargetDiff describes a target (the Minecraft version, or a modloader)
+// that differs between two pack versions. Should the target have been
+// added or removed, the respective version will be empty.
+type TargetDiff struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	FromVersion string `json:"fromVersion,omitempty"`
+	ToVersion   string `json:"toVersion,omitempty"`
+}
+
+// VersionDiff describes everything that differs between two pack
+// versions.
+type VersionDiff struct {
+	Targets []*TargetDiff `json:"targets"`
+	Files   []*FileDiff   `json:"files"`
+}
+
+// FilesWith gets the changed files that have the given change.
+func (d *VersionDiff) FilesWith(change FileChange) []*FileDiff {
+	var files []*FileDiff
+	for _, file := range d.Files {
+		if file.Change == change {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Configs gets the config files that have been added, removed or changed.
+func (d *VersionDiff) Configs() []*FileDiff {
+	var files []*FileDiff
+	for _, file := range d.Files {
+		if file.Config {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// DiffVersions compares the files and targets of the two given versions
+// of a pack. Files are matched by their path and name, and compared by
+// their sha1 hash.
+func DiffVersions(from *modpacksch.PackVersion, to *modpacksch.PackVersion) *VersionDiff {
+	return diff(
+		packFiles(from.Files, nil), packFiles(to.Files, nil),
+		packTargets(from.Targets), packTargets(to.Targets),
+	)
+}
+
+// DiffInstall compares the pack installed at the given destination with
+// the given version of the pack, using the files recorded when it was
+// installed. Only files for the install's target are compared.
+// Installs made before targets were recorded will have no target
+// changes.
+func (i *Installer) DiffInstall(dest string, to *modpacksch.PackVersion) (*VersionDiff, error) {
+	settings, err := i.GetInstallSettings(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	fromTargets := map[string]*InstalledTarget{}
+	for _, target := range settings.Targets {
+		fromTargets[target.Name] = target
+	}
+	toTargets := packTargets(to.Targets)
+	if len(settings.Targets) == 0 {
+		toTargets = map[string]*InstalledTarget{}
+	}
+
+	return diff(settings.Files, packFiles(to.Files, &settings.Target), fromTargets, toTargets), nil
+}
+
+// Gets the sha1 hashes of the given files, by their path. Should a target
+// be given, files for the other target are excluded.
+func packFiles(files []*modpacksch.File, target *minecraft.InstallTarget) map[string]string {
+	hashes := map[string]string{}
+	for _, file := range files {
+		if target != nil && ((*target == minecraft.Client && file.ServerOnly) || (*target == minecraft.Server && file.ClientOnly)) {
+			continue
+		}
+		hashes[file.Path+file.Name] = file.Sha1
+	}
+	return hashes
+}
+
+// Gets the given targets, by their name.
+func packTargets(targets []*modpacksch.Target) map[string]*InstalledTarget {
+	installed := map[string]*InstalledTarget{}
+	for _, target := range targets {
+		installed[target.Name] = &InstalledTarget{
+			Name:    target.Name,
+			Type:    target.Type,
+			Version: target.Version,
+		}
+	}
+	return installed
+}
+
+func diff(fromFiles map[string]string, toFiles map[string]string, fromTargets map[string]*InstalledTarget, toTargets map[string]*InstalledTarget) *VersionDiff {
+	versionDiff := &VersionDiff{
+		Targets: []*TargetDiff{},
+		Files:   []*FileDiff{},
+	}
+
+	for name, from := range fromTargets {
+		to, ok := toTargets[name]
+		if !ok {
+			versionDiff.Targets = append(versionDiff.Targets, &TargetDiff{
+				Name:        name,
+				Type:        from.Type,
+				FromVersion: from.Version,
+			})
+		} else if to.Version != from.Version {
+			versionDiff.Targets = append(versionDiff.Targets, &TargetDiff{
+				Name:        name,
+				Type:        to.Type,
+				FromVersion: from.Version,
+				ToVersion:   to.Version,
+			})
+		}
+	}
+	for name, to := range toTargets {
+		if _, ok := fromTargets[name]; !ok {
+			versionDiff.Targets = append(versionDiff.Targets, &TargetDiff{
+				Name:      name,
+				Type:      to.Type,
+				ToVersion: to.Version,
+			})
+		}
+	}
+	sort.Slice(versionDiff.Targets, func(a, b int) bool {
+		return versionDiff.Targets[a].Name < versionDiff.Targets[b].Name
+	})
+
+	for path, fromSha1 := range fromFiles {
+		toSha1, ok := toFiles[path]
+		if !ok {
+			versionDiff.Files = append(versionDiff.Files, &FileDiff{
+				Path:     path,
+				Change:   FileRemoved,
+				FromSha1: fromSha1,
+			})
+		} else if toSha1 != fromSha1 {
+			versionDiff.Files = append(versionDiff.Files, &FileDiff{
+				Path:     path,
+				Change:   FileChanged,
+				FromSha1: fromSha1,
+				ToSha1:   toSha1,
+			})
+		}
+	}
+	for path, toSha1 := range toFiles {
+		if _, ok := fromFiles[path]; !ok {
+			versionDiff.Files = append(versionDiff.Files, &FileDiff{
+				Path:   path,
+				Change: FileAdded,
+				ToSha1: toSha1,
+			})
+		}
+	}
+	for _, file := range versionDiff.Files {
+		file.Config = isConfig(file.Path)
+	}
+	sort.Slice(versionDiff.Files, func(a, b int) bool {
+		return versionDiff.Files[a].Path < versionDiff.Files[b].Path
+	})
+
+	return versionDiff
+}
+
+// Whether the file at the given path (within the pack) is a config.
+func isConfig(path string) bool {
+	first := strings.SplitN(filepath.ToSlash(filepath.Clean(path)), "/", 2)[0]
+	return first == "config" || first == "defaultconfigs" || isMergeable(path)
+}
@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+)
+
+func TestDiffVersions(t *testing.T) {
+	from := &modpacksch.PackVersion{
+		Targets: []*modpacksch.Target{
+			{Type: "game", Name: "minecraft", Version: "1.16.5"},
+			{Type: "modloader", Name: "forge", Version: "36.1.0"},
+		},
+		Files: []*modpacksch.File{
+			{Path: "./mods/", Name: "same.jar", Sha1: helloSha1},
+			{Path: "./mods/", Name: "removed.jar", Sha1: helloSha1},
+			{Path: "./config/", Name: "changed.cfg", Sha1: helloSha1},
+		},
+	}
+	to := &modpacksch.PackVersion{
+		Targets: []*modpacksch.Target{
+			{Type: "game", Name: "minecraft", Version: "1.16.5"},
+			{Type: "modloader", Name: "forge", Version: "36.2.0"},
+		},
+		Files: []*modpacksch.File{
+			{Path: "./mods/", Name: "same.jar", Sha1: helloSha1},
+			{Path: "./mods/", Name: "added.jar", Sha1: worldSha1},
+			{Path: "./config/", Name: "changed.cfg", Sha1: worldSha1},
+		},
+	}
+
+	diff := DiffVersions(from, to)
+
+	if len(diff.Targets) != 1 || diff.Targets[0].Name != "forge" ||
+		diff.Targets[0].FromVersion != "36.1.0" || diff.Targets[0].ToVersion != "36.2.0" {
+		t.Errorf("expected forge to change from 36.1.0 to 36.2.0, got %+v", diff.Targets)
+	}
+
+	expected := map[string]FileChange{
+		"./mods/added.jar":     FileAdded,
+		"./mods/removed.jar":   FileRemoved,
+		"./config/changed.cfg": FileChanged,
+	}
+	if len(diff.Files) != len(expected) {
+		t.Errorf("expected %d changed files, got %d", len(expected), len(diff.Files))
+	}
+	for _, file := range diff.Files {
+		if change, ok := expected[file.Path]; !ok || change != file.Change {
+			t.Errorf("%s should be %s, but was %s", file.Path, change, file.Change)
+		}
+	}
+
+	configs := diff.Configs()
+	if len(configs) != 1 || configs[0].Path != "./config/changed.cfg" {
+		t.Errorf("expected only ./config/changed.cfg to be a config, got %+v", configs)
+	}
+}
@@ -5,24 +5,23 @@
 package ftb
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
+	"sync"
 
 	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
-	"github.com/gammazero/workerpool"
-	"github.com/google/uuid"
+	"github.com/jamiemansfield/mcinstall/fabric"
 	"github.com/jamiemansfield/mcinstall/forge"
+	"github.com/jamiemansfield/mcinstall/liteloader"
 	"github.com/jamiemansfield/mcinstall/minecraft"
 	"github.com/jamiemansfield/mcinstall/minecraft/launcher"
+	"github.com/jamiemansfield/mcinstall/quilt"
 )
 
 const (
@@ -33,20 +32,47 @@ const (
 
 var (
 	OtherPackAlreadyInstalled = errors.New("ftb: a pack is already installed at this location")
+	NoPackInstalled           = errors.New("ftb: no pack is installed at this location")
 )
 
+// Installer installs packs from modpacks.ch.
+// An Installer may be reused for multiple installs, and may be used
+// concurrently for installs to different destinations.
 type Installer struct {
 	// The directory to store mcinstall-related files (settings.json, etc)
 	DataDir string
 
 	// Directories that shouldn't be touched, in any capacity, when updating
-	// modpacks.
+	// modpacks. Unlike Protect rules, these can't be un-protected.
 	ExcludedDirs []string
 
+	// Gitignore-style patterns of files that shouldn't be touched, in any
+	// capacity, when updating modpacks. These are applied after those in
+	// an install's .mcinstallignore file.
+	Protect []string
+
 	// The Minecraft Forge installer to use, should it be needed
 	ForgeInstaller *forge.Installer
 
-	workerPool *workerpool.WorkerPool
+	// The Fabric Loader installer to use, should it be needed
+	FabricInstaller *fabric.Installer
+
+	// The Quilt Loader installer to use, should it be needed
+	QuiltInstaller *quilt.Installer
+
+	// The LiteLoader installer to use, should it be needed
+	LiteLoaderInstaller *liteloader.Installer
+
+	// Determines how many files may fail to install, before an install
+	// is aborted.
+	FailurePolicy FailurePolicy
+
+	// The maximum number of files to download at once, for each install
+	MaxWorkers int
+
+	// Guards the launcher directory, which is shared between all client
+	// installs
+	launcherLock sync.Mutex
 }
 
 func NewInstaller(maxWorkers int) *Installer {
@@ -55,206 +81,255 @@ func NewInstaller(maxWorkers int) *Installer {
 		ExcludedDirs: []string{
 			"saves",
 		},
-		ForgeInstaller: forge.NewInstaller(),
-		workerPool:     workerpool.New(maxWorkers),
+		ForgeInstaller:      forge.NewInstaller(),
+		FabricInstaller:     fabric.NewInstaller(),
+		QuiltInstaller:      quilt.NewInstaller(),
+		LiteLoaderInstaller: liteloader.NewInstaller(),
+		FailurePolicy:       BestEffort,
+		MaxWorkers:          maxWorkers,
 	}
 }
 
 // IsExcludedDir determines whether a directory should be excluded from being
 // changed as the result of any update logic.
+//
+// Deprecated: ExcludedDirs are applied as protect rules, along with any
+// others, by every install - this only considers ExcludedDirs and DataDir.
 func (i *Installer) IsExcludedDir(relPath string) bool {
-	parts := strings.Split(relPath, string(filepath.Separator))
-
-	excludedDirs := append(i.ExcludedDirs, i.DataDir)
-	for _, excludedDir := range excludedDirs {
-		if parts[0] == excludedDir {
-			return true
-		}
-	}
-
-	return false
+	// The rules only match directories, so match something within it
+	return matchProtectRules(i.excludedDirRules(), filepath.Join(relPath, "_")) != nil
 }
 
 // Installs the given pack version to the destination, with the
 // appropriate files for that install target.
 func (i *Installer) InstallPackVersion(installTarget minecraft.InstallTarget, dest string, pack *modpacksch.Pack, version *modpacksch.PackVersion) error {
+	return i.InstallPackVersionContext(context.Background(), installTarget, dest, pack, version)
+}
+
+// See InstallPackVersion
+// Should the context be cancelled, no further files will be downloaded,
+// in-flight downloads will be aborted (and their partial files removed)
+// and the existing install settings will be left untouched - so the next
+// install will pick up from where this one left off.
+func (i *Installer) InstallPackVersionContext(ctx context.Context, installTarget minecraft.InstallTarget, dest string, pack *modpacksch.Pack, version *modpacksch.PackVersion) error {
 	fmt.Println("Installing " + pack.Name + " v" + version.Name + "...")
 
+	// Roll back any changes from an interrupted install, before working
+	// out what needs doing
 	destination, err := filepath.Abs(dest)
 	if err != nil {
 		return err
 	}
+	if err := i.recoverTransaction(destination); err != nil {
+		return err
+	}
 
-	// Find existing install (or create one)
-	if err := os.MkdirAll(i.DataDir, os.ModePerm); err != nil {
+	plan, err := i.planPackVersion(ctx, installTarget, destination, pack, version)
+	if err != nil {
 		return err
 	}
+	if !plan.Fresh {
+		fmt.Println("Existing installation of " + strconv.Itoa(plan.Settings.Pack) + " v" + strconv.Itoa(plan.Settings.Version) + " detected")
+	}
 
-	var settings *InstallSettings
-	if readJson(filepath.Join(destination, i.DataDir, settingsFile), &settings) != nil {
-		settings = &InstallSettings{
-			ID:      uuid.New().String(),
-			Pack:    pack.ID,
-			Version: version.ID,
-			Target:  installTarget,
-			Files:   map[string]string{},
-		}
-	} else {
-		fmt.Println("Existing installation of " + strconv.Itoa(settings.Pack) + " v" + strconv.Itoa(settings.Version) + " detected")
+	return i.installPlan(ctx, plan)
+}
 
-		if pack.ID != settings.Pack {
-			return OtherPackAlreadyInstalled
-		}
+// Installs the given plan.
+// All changes to the install are staged, and then committed at once - so
+// should any part of the install fail, the install is left as it was.
+// Modloaders can't be staged, as their installers write in place, so only
+// the top-level files of a server are restored should the install fail.
+// Anything a modloader adds elsewhere (libraries, and for clients the
+// launcher's versions) is left in place, to be reused by the next install.
+func (i *Installer) installPlan(ctx context.Context, plan *Plan) error {
+	destination := plan.Destination
+	settings := plan.Settings
+
+	if err := os.MkdirAll(filepath.Join(destination, i.DataDir), os.ModePerm); err != nil {
+		return err
 	}
 	install := &Install{
-		Version:       version.ID,
-		OriginalFiles: settings.Files,
+		Version:       plan.Version.ID,
+		OriginalFiles: settings.knownFiles(),
 		NewFiles:      map[string]string{},
+		dest:          destination,
 	}
 
-	if err := i.InstallTargets(installTarget, destination, version.Targets); err != nil {
-		return err
-	}
-	if err := i.InstallFiles(install, installTarget, destination, version.Files); err != nil {
+	printLowMemoryWarning(plan.Memory)
+
+	tx, err := i.beginTransaction(destination)
+	if err != nil {
 		return err
 	}
 
-	// Remove any unmodified files that are no longer apart the pack
-	err = filepath.Walk(destination, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	// Modloaders are installed in place, so snapshot the top-level files
+	// of servers (launch scripts, server jars, etc) that their installers
+	// may change
+	if plan.Target == minecraft.Server {
+		if err := tx.snapshot(); err != nil {
+			tx.discard()
 			return err
 		}
-		if info.IsDir() {
-			return nil
-		}
+	}
+	if err := i.InstallTargetsContext(ctx, plan.Target, destination, plan.Version.Targets); err != nil {
+		tx.abort()
+		return err
+	}
 
-		relPath, err := filepath.Rel(destination, path)
-		if err != nil {
-			return err
+	// Generate the server's launch configuration, using the pack's memory
+	// specs
+	launchConfig, err := writeLaunchConfig(tx, settings, plan.LaunchFiles)
+	if err != nil {
+		tx.abort()
+		return err
+	}
+	for _, file := range plan.LaunchFiles {
+		if file.Keep {
+			fmt.Printf("%s has been modified, or is protected - we have left it in place.\n", file.Name)
 		}
-		ftbPath := "./" + filepath.ToSlash(relPath)
+	}
 
-		// While this should never be an issue anyway - for peace of mind,
-		// ftbinstall will not delete ANYTHING from a protected directory.
-		if i.IsExcludedDir(relPath) {
-			return nil
+	// Should some files fail to install, while still within the failure
+	// policy, carry on with the install - reporting the failures at the end.
+	var files []*PlannedFile
+	for _, file := range plan.Files {
+		if file.File != nil {
+			files = append(files, file)
 		}
-
-		// Ignore if its a current file
-		if install.NewFiles[ftbPath] != "" {
-			return nil
+	}
+	filesErr := i.installFiles(ctx, install, files, tx)
+	if filesErr != nil {
+		if e, ok := filesErr.(*FilesError); !ok || e.Aborted {
+			tx.abort()
+			return filesErr
 		}
+	}
 
-		// Check if its been modified
-		if install.OriginalFiles[ftbPath] != "" {
-			f, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer f.Close()
-
-			hasher := sha1.New()
-			if _, err := io.Copy(hasher, f); err != nil {
-				return err
-			}
-			hash := hex.EncodeToString(hasher.Sum(nil))
-
-			// Delete file
-			if hash == install.OriginalFiles[ftbPath] {
-				fmt.Printf("%s has been removed from the modpack, as its\n", ftbPath)
-				fmt.Println("sha1 hash matches the original, it has been removed.")
-				return os.Remove(path)
-			}
-
-			// The file has been removed from the pack, but the player has modified it
-			fmt.Printf("%s has been removed from the modpack, as its\n", ftbPath)
-			fmt.Println("sha1 hash doesn't match the original - we have left it in place.")
-			fmt.Println("Please investigate whether you still need the file before playing!")
-			fmt.Printf("You can remove the '%s' line from %s/%s if\n", ftbPath, i.DataDir, settingsFile)
-			fmt.Println("still required")
-
-			// So that this message continues on, store the original hash in the new file list
-			install.NewFiles[ftbPath] = install.OriginalFiles[ftbPath]
+	// Remove any unmodified files that are no longer apart the pack
+	for _, file := range plan.FilesFor(DeleteFile) {
+		fmt.Printf("%s has been removed from the modpack, as its\n", file.Path)
+		fmt.Println("sha1 hash matches the original, it has been removed.")
+		if err := tx.remove(file.Dest); err != nil {
+			tx.abort()
+			return err
+		}
+	}
+	for _, file := range plan.FilesFor(ProtectFile) {
+		if file.File != nil {
+			continue
 		}
 
-		return nil
-	})
+		// The file has been removed from the pack, but is protected
+		fmt.Printf("%s has been removed from the modpack, but is protected by %s - we have left it in place.\n", file.Path, file.Rule)
+		install.NewFiles[file.Path] = file.OriginalHash
+	}
+	for _, file := range plan.FilesFor(KeepFile) {
+		// The file has been removed from the pack, but the player has modified it
+		fmt.Printf("%s has been removed from the modpack, as its\n", file.Path)
+		fmt.Println("sha1 hash doesn't match the original - we have left it in place.")
+		fmt.Println("Please investigate whether you still need the file before playing!")
+		fmt.Printf("You can remove the '%s' line from %s/%s if\n", file.Path, i.DataDir, settingsFile)
+		fmt.Println("still required")
+
+		// So that this message continues on, store the original hash in the new file list
+		install.NewFiles[file.Path] = file.OriginalHash
+	}
+
+	// Write install settings
+	settings.Version = install.Version
+	settings.Files = install.NewFiles
+	settings.FailedFiles = install.FailedFiles
+	settings.LaunchConfig = launchConfig
+	settings.Targets = nil
+	for _, target := range plan.Version.Targets {
+		settings.Targets = append(settings.Targets, &InstalledTarget{
+			Name:    target.Name,
+			Type:    target.Type,
+			Version: target.Version,
+		})
+	}
+	if plan.Profile != nil {
+		settings.ProfileVersion = plan.Profile.Version
+	}
+	settingsPath := filepath.Join(destination, i.DataDir, settingsFile)
+	stagedSettingsPath, err := tx.stagingPath(settingsPath)
 	if err != nil {
+		tx.abort()
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(stagedSettingsPath), os.ModePerm); err != nil {
+		tx.abort()
+		return err
+	}
+	if err := writeJson(stagedSettingsPath, &settings); err != nil {
+		tx.abort()
+		return err
+	}
+	if err := tx.write(settingsPath); err != nil {
+		tx.abort()
 		return err
 	}
 
-	// Install profile for the Minecraft launcher
-	if installTarget == minecraft.Client {
-		// Get the target Minecraft version for the pack
-		var mcVersion *minecraft.Version
-		for _, target := range version.Targets {
-			if target.Type == "game" {
-				ver, err := minecraft.ParseVersion(target.Version)
-				if err != nil {
-					return err
-				}
-				mcVersion = ver
-				break
-			}
-		}
-
-		// If we can't determine the game version, we can't really proceed
-		if mcVersion == nil {
-			return FailedToDetermineGameVersion
-		}
+	// Move everything into place
+	if err := tx.commit(); err != nil {
+		return err
+	}
 
-		// Create profile
-		profile := &launcher.Profile{
-			Name:    pack.Name + " " + version.Name,
-			Type:    "custom",
-			GameDir: destination,
-		}
+	// Install profile for the Minecraft launcher
+	if plan.Profile != nil {
+		profile := *plan.Profile
 
 		// Add icon to pack
-		icon, err := launcher.CreateIconFromURL(pack.GetIcon().URL)
+		icon, err := launcher.CreateIconFromURLContext(ctx, plan.Pack.GetIcon().URL)
 		if err != nil {
 			fmt.Printf("Failed to get pack icon: %e", err)
 		} else {
 			profile.Icon = icon
 		}
 
-		// Set profile version, based on modloader in use
-		for _, target := range version.Targets {
-			if target.Type == "modloader" {
-				// Minecraft Forge
-				if target.Name == "forge" {
-					var forgeVersion string
-					// Minecraft 1.13 and above
-					if mcVersion.Major >= 1 && mcVersion.Minor >= 13 {
-						forgeVersion = mcVersion.String() + "-forge-" + target.Version
-					} else {
-						forgeVersion = mcVersion.String() + "-forge" + mcVersion.String() + "-" + target.Version
-					}
-
-					profile.Version = forgeVersion
-					break
-				}
-
-				// todo: other modloaders
+		// Install profile
+		if err := launcher.InstallProfile(settings.ID, &profile); err != nil {
+			if rollbackErr := tx.rollback(); rollbackErr != nil {
+				return fmt.Errorf("%v (rollback failed: %v)", err, rollbackErr)
 			}
+			return err
 		}
+	}
 
-		// Install profile
-		if err := launcher.InstallProfile(settings.ID, profile); err != nil {
+	if err := tx.finish(); err != nil {
+		return err
+	}
+	i.printMergeReport(install)
+
+	// Only keep the originals of files still a part of the install
+	if err := i.prunePristine(destination, settings.knownFiles()); err != nil {
+		return err
+	}
+
+	// Partial downloads are only needed should a file have failed
+	if filesErr == nil {
+		if err := os.RemoveAll(filepath.Join(destination, i.DataDir, partsDir)); err != nil {
 			return err
 		}
 	}
 
-	// Write install settings
-	settings.Version = install.Version
-	settings.Files = install.NewFiles
-	return writeJson(filepath.Join(destination, i.DataDir, settingsFile), &settings)
+	return filesErr
 }
 
 type Install struct {
 	Version       int
 	OriginalFiles map[string]string
 	NewFiles      map[string]string
+
+	// What we knew of the files that failed to install, from the previous
+	// install
+	FailedFiles map[string]string
+
+	dest      string
+	mu        sync.Mutex
+	merged    []string
+	conflicts []string
 }
 
 // ftbinstall.json
@@ -264,6 +339,42 @@ type InstallSettings struct {
 	Version int                     `json:"version"`
 	Target  minecraft.InstallTarget `json:"target"`
 	Files   map[string]string       `json:"files"`
+
+	// The launcher version used by the pack's profile, for client installs
+	ProfileVersion string `json:"profileVersion,omitempty"`
+
+	// The Minecraft version and modloaders the pack was installed with
+	Targets []*InstalledTarget `json:"targets,omitempty"`
+
+	// The sha1 hashes of the launch configuration generated for servers,
+	// so that modified files are left alone
+	LaunchConfig map[string]string `json:"launchConfig,omitempty"`
+
+	// The sha1 hashes, from the previous install, of files that failed to
+	// install. They aren't a part of Files, so are retried by the next
+	// install - but a player's changes to them are still recognised.
+	FailedFiles map[string]string `json:"failedFiles,omitempty"`
+}
+
+// Gets every file known to have been installed, including those that
+// later failed to update.
+func (s *InstallSettings) knownFiles() map[string]string {
+	files := map[string]string{}
+	for path, hash := range s.FailedFiles {
+		files[path] = hash
+	}
+	for path, hash := range s.Files {
+		files[path] = hash
+	}
+	return files
+}
+
+// InstalledTarget records a target (the Minecraft version, or a modloader)
+// of an installed pack version.
+type InstalledTarget struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Version string `json:"version"`
 }
 
 func readJson(destination string, v interface{}) error {
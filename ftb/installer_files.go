@@ -5,114 +5,270 @@
 package ftb
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
 	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/gammazero/workerpool"
 	"github.com/jamiemansfield/mcinstall/minecraft"
 	"github.com/jamiemansfield/mcinstall/util"
 )
 
+const (
+	// The directory, within DataDir, that partial downloads are kept - so
+	// they can be resumed should the install be interrupted.
+	partsDir = "parts"
+)
+
+// FailurePolicy determines how many files are allowed to fail to install,
+// before the install is aborted.
+type FailurePolicy int
+
+const (
+	// Abort the install as soon as any file fails to install.
+	FailFast FailurePolicy = 0
+
+	// Install as many files as possible, regardless of how many fail.
+	BestEffort FailurePolicy = -1
+)
+
+// TolerateFailures creates a FailurePolicy that allows up to n files to
+// fail to install, before the install is aborted.
+func TolerateFailures(n int) FailurePolicy {
+	return FailurePolicy(n)
+}
+
+// Whether the given number of failed files is acceptable.
+func (p FailurePolicy) allows(failures int) bool {
+	return p < 0 || failures <= int(p)
+}
+
+// FileError describes a file that failed to install.
+type FileError struct {
+	// The path of the file, relative to the install directory
+	Path string
+	// The URL the file was being downloaded from
+	URL string
+	// The cause of the failure
+	Err error
+}
+
+func (e *FileError) Error() string {
+	return "failed to install '" + e.Path + "' from " + e.URL + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// FilesError is returned from InstallFiles, should any files fail to
+// install.
+type FilesError struct {
+	Errors []*FileError
+
+	// Whether the install was aborted, as more files failed than the
+	// installer's FailurePolicy allows.
+	Aborted bool
+}
+
+func (e *FilesError) Error() string {
+	var b strings.Builder
+	b.WriteString("ftb: " + strconv.Itoa(len(e.Errors)) + " file(s) failed to install")
+	if e.Aborted {
+		b.WriteString(", install aborted")
+	}
+	for _, err := range e.Errors {
+		b.WriteString("\n\t" + err.Error())
+	}
+	return b.String()
+}
+
 // Installs the given files, for the target environment, to the given
 // destination.
+// Files that fail to install are reported through a *FilesError, and are
+// not recorded in the install's NewFiles - so they will be retried by the
+// next install. What was known of them from the previous install is kept
+// in the install's FailedFiles instead. Whether the install continues after a file fails is
+// determined by the installer's FailurePolicy.
 func (i *Installer) InstallFiles(install *Install, target minecraft.InstallTarget, dest string, files []*modpacksch.File) error {
-	// Collect the target-specific files, so we can keep an accurate count
-	// of how many files we've installed.
-	var targetFiles []*modpacksch.File
-	for _, file := range files {
-		// Ignore files for another target
-		if (target == minecraft.Client && file.ServerOnly) || (target == minecraft.Server && file.ClientOnly) {
-			continue
-		}
-		targetFiles = append(targetFiles, file)
+	return i.InstallFilesContext(context.Background(), install, target, dest, files)
+}
+
+// See InstallFiles
+// Should the context be cancelled, no further files will be downloaded and
+// any in-flight downloads will be aborted.
+func (i *Installer) InstallFilesContext(ctx context.Context, install *Install, target minecraft.InstallTarget, dest string, files []*modpacksch.File) error {
+	install.dest = dest
+
+	rules, err := i.loadProtectRules(dest)
+	if err != nil {
+		return err
 	}
+	planned, err := i.planFiles(ctx, install, target, dest, files, rules)
+	if err != nil {
+		return err
+	}
+	return i.installFiles(ctx, install, planned, nil)
+}
+
+// Installs the given planned files.
+// Should a transaction be given, files will be staged to it rather than
+// written in place.
+func (i *Installer) installFiles(ctx context.Context, install *Install, files []*PlannedFile, tx *transaction) error {
+	// Used to stop any remaining downloads, should too many files fail
+	filesCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	filesErr := &FilesError{}
+
+	// Each install gets its own pool, so the installer can be reused
+	pool := workerpool.New(i.MaxWorkers)
 
 	// Install files for the target
-	for j, file := range targetFiles {
+	for j, file := range files {
 		j := j
 		file := file
 
-		i.workerPool.Submit(func() {
-			msg, err := i.installFile(install, dest, file)
+		pool.Submit(func() {
+			// Don't start any new downloads once cancelled
+			if filesCtx.Err() != nil {
+				return
+			}
+
+			msg, hash, err := i.installFile(filesCtx, install, file, tx)
+
+			mu.Lock()
+			defer mu.Unlock()
+
 			if err != nil {
-				fmt.Printf("[%d / %d] Failed to install '%s%s', ignoring file...\n", j+1, len(targetFiles), file.Path, file.Name)
+				if filesCtx.Err() != nil {
+					return
+				}
+				fmt.Printf("[%d / %d] Failed to install '%s'\n", j+1, len(files), file.Path)
 				fmt.Println(err)
+
+				filesErr.Errors = append(filesErr.Errors, &FileError{
+					Path: file.Path,
+					URL:  file.File.URL,
+					Err:  err,
+				})
+				if !i.FailurePolicy.allows(len(filesErr.Errors)) {
+					filesErr.Aborted = true
+					cancel()
+				}
+
+				// Keep what we knew of the file from the previous
+				// install, so a modified file isn't later mistaken as
+				// being unknown to us.
+				if file.OriginalHash != "" {
+					if install.FailedFiles == nil {
+						install.FailedFiles = map[string]string{}
+					}
+					install.FailedFiles[file.Path] = file.OriginalHash
+				}
 				return
 			}
-			fmt.Printf("[%d / %d] %s\n", j+1, len(targetFiles), msg)
-		})
+			fmt.Printf("[%d / %d] %s\n", j+1, len(files), msg)
 
-		// Log the files information in the install settings
-		install.NewFiles[file.Path+file.Name] = file.Sha1
+			// Log the files information in the install settings
+			if hash != "" {
+				install.NewFiles[file.Path] = hash
+			}
+		})
 	}
 
-	i.workerPool.StopWait()
+	pool.StopWait()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(filesErr.Errors) > 0 {
+		sort.Slice(filesErr.Errors, func(a, b int) bool {
+			return filesErr.Errors[a].Path < filesErr.Errors[b].Path
+		})
+		return filesErr
+	}
 	return nil
 }
 
-// Installs the given file, to the destination
-func (i *Installer) installFile(install *Install, dest string, file *modpacksch.File) (string, error) {
-	dirPath := filepath.Join(dest, filepath.FromSlash(file.Path))
-	fileDest := filepath.Join(dirPath, file.Name)
-
-	// If file already exists, check the checksum
-	if _, err := os.Stat(fileDest); err == nil {
-		f, err := os.Open(fileDest)
-		if err != nil {
-			return "", err
-		}
-		defer f.Close()
-
-		hasher := sha1.New()
-		if _, err := io.Copy(hasher, f); err != nil {
-			return "", err
-		}
-		hash := hex.EncodeToString(hasher.Sum(nil))
-
-		// If already exists, continue to next file
-		if hash == file.Sha1 {
-			return fmt.Sprintf("%s%s found, skipping...", file.Path, file.Name), nil
+// Installs the given planned file, returning the hash to record for it
+func (i *Installer) installFile(ctx context.Context, install *Install, file *PlannedFile, tx *transaction) (string, string, error) {
+	switch file.Action {
+	case SkipFile:
+		// Keep the original, should the player later modify it
+		if err := i.storePristineFile(install.dest, file.File.Sha1, file.Dest); err != nil {
+			return "", "", err
 		}
+		return fmt.Sprintf("%s found, skipping...", file.Path), file.File.Sha1, nil
+	case ProtectFile:
+		// Keep what we knew of the file, should it later be unprotected
+		return fmt.Sprintf("%s is protected by %s, skipping...", file.Path, file.Rule), file.OriginalHash, nil
+	case MergeFile:
+		return i.mergeFile(ctx, install, file, tx)
+	case DivertFile:
+		// Don't override if the player made changes
+		fmt.Println("************************************************************************************************")
+		fmt.Printf("%s has a sha1 has of '%s', when\n", file.Path, file.Hash)
+		fmt.Printf("'%s' was expected.\n", file.OriginalHash)
+		fmt.Printf("To prevent overriding configurations, it will be installed under %s/%d.\n", i.DataDir, install.Version)
+		fmt.Println("Please investigate any collisions before playing!")
+		fmt.Println("************************************************************************************************")
+	}
 
-		// If the file previously existed, don't override if the player made changes
-		originalHash := install.OriginalFiles[file.Path+file.Name]
-		if originalHash != "" && hash != originalHash {
-			fmt.Println("************************************************************************************************")
-			fmt.Printf("%s%s has a sha1 has of '%s', when\n", file.Path, file.Name, hash)
-			fmt.Printf("'%s' was expected.\n", originalHash)
-			fmt.Printf("To prevent overriding configurations, it will be installed under %s/%d.\n", i.DataDir, install.Version)
-			fmt.Println("Please investigate any collisions before playing!")
-			fmt.Println("************************************************************************************************")
-
-			return i.installFile(install, filepath.Join(dest, i.DataDir, strconv.Itoa(install.Version)), file)
+	fileDest := file.Dest
+	if tx != nil {
+		stagingPath, err := tx.stagingPath(file.Dest)
+		if err != nil {
+			return "", "", err
 		}
+		fileDest = stagingPath
 	}
 
 	// Ensure directory exists
-	if err := os.MkdirAll(dirPath, os.ModePerm); err != nil {
-		return "", err
+	if err := os.MkdirAll(filepath.Dir(fileDest), os.ModePerm); err != nil {
+		return "", "", err
 	}
 
 	// GET the file
-	req, err := util.NewRequest(http.MethodGet, file.URL, nil)
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, file.File.URL, nil)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	req.Header.Set("Accept", "*")
 
-	// Write file to disk
-	f, err := os.Create(fileDest)
+	// Write file to disk, verifying it against the pack's sha1 hash
+	hash, err := util.DownloadResumable(req, fileDest, i.partPath(install.dest, file), file.File.Sha1, int64(file.File.Size))
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	if tx != nil {
+		if err := tx.write(file.Dest); err != nil {
+			return "", "", err
+		}
+	}
+
+	// Keep the original, should the player later modify it
+	if err := i.storePristineFile(install.dest, hash, fileDest); err != nil {
+		return "", "", err
 	}
-	defer f.Close()
 
-	return fmt.Sprintf("Installed '%s' to '%s'", file.Name, file.Path), util.Download(f, req)
+	return fmt.Sprintf("Installed '%s' to '%s'", file.File.Name, file.File.Path), hash, nil
+}
+
+// Gets the path that the given file is downloaded to, before being moved
+// into place. Partial downloads are kept outside of the transaction, so
+// they survive it being discarded.
+func (i *Installer) partPath(dest string, file *PlannedFile) string {
+	if file.File.Sha1 != "" {
+		return filepath.Join(dest, i.DataDir, partsDir, file.File.Sha1+util.PartSuffix)
+	}
+	return filepath.Join(dest, i.DataDir, partsDir, filepath.FromSlash(file.Path)+util.PartSuffix)
 }
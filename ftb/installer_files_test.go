@@ -0,0 +1,124 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+func TestInstallFilesCancel(t *testing.T) {
+	// Serves part of the file, and then stalls until the request is
+	// aborted
+	started := make(chan struct{})
+	var once sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		once.Do(func() {
+			close(started)
+		})
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	dest, err := ioutil.TempDir("", "ftbcancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	installer := NewInstaller(1)
+	install := &Install{
+		Version:       1,
+		OriginalFiles: map[string]string{},
+		NewFiles:      map[string]string{},
+	}
+	files := []*modpacksch.File{
+		{Path: "./mods/", Name: "stalled.jar", URL: server.URL + "/stalled.jar", Sha1: helloSha1, Size: 10},
+		{Path: "./mods/", Name: "queued.jar", URL: server.URL + "/queued.jar", Sha1: worldSha1, Size: 10},
+	}
+
+	err = installer.InstallFilesContext(ctx, install, minecraft.Client, dest, files)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the install to be cancelled, got %v", err)
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(dest, "mods", file.Name)); !os.IsNotExist(err) {
+			t.Errorf("%s shouldn't have been put in place", file.Name)
+		}
+	}
+	if len(install.NewFiles) != 0 {
+		t.Errorf("no files should have been recorded, got %v", install.NewFiles)
+	}
+}
+
+func TestInstallFilesFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok.jar" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	dest, err := ioutil.TempDir("", "ftbfailed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+	writeTestFile(t, dest, "mods/failed.jar", "hello")
+
+	installer := NewInstaller(1)
+	install := &Install{
+		Version: 2,
+		OriginalFiles: map[string]string{
+			"./mods/failed.jar": helloSha1,
+		},
+		NewFiles: map[string]string{},
+	}
+	files := []*modpacksch.File{
+		{Path: "./mods/", Name: "ok.jar", URL: server.URL + "/ok.jar", Sha1: helloSha1, Size: 5},
+		{Path: "./mods/", Name: "failed.jar", URL: server.URL + "/failed.jar", Sha1: worldSha1, Size: 5},
+	}
+
+	err = installer.InstallFilesContext(context.Background(), install, minecraft.Client, dest, files)
+	var filesErr *FilesError
+	if !errors.As(err, &filesErr) || len(filesErr.Errors) != 1 || filesErr.Errors[0].Path != "./mods/failed.jar" {
+		t.Fatalf("expected failed.jar to fail, got %v", err)
+	}
+
+	// The failed file is retried by the next install, but what we knew
+	// of it is kept
+	if _, ok := install.NewFiles["./mods/failed.jar"]; ok {
+		t.Error("failed.jar shouldn't have been recorded in NewFiles")
+	}
+	if install.NewFiles["./mods/ok.jar"] != helloSha1 {
+		t.Errorf("ok.jar should have been recorded in NewFiles, got %v", install.NewFiles)
+	}
+	if install.FailedFiles["./mods/failed.jar"] != helloSha1 {
+		t.Errorf("failed.jar should have been recorded in FailedFiles, got %v", install.FailedFiles)
+	}
+}
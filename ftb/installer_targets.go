@@ -5,11 +5,16 @@
 package ftb
 
 import (
+	"context"
 	"errors"
 
 	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/fabric"
+	"github.com/jamiemansfield/mcinstall/forge"
+	"github.com/jamiemansfield/mcinstall/liteloader"
 	"github.com/jamiemansfield/mcinstall/minecraft"
 	"github.com/jamiemansfield/mcinstall/minecraft/launcher"
+	"github.com/jamiemansfield/mcinstall/quilt"
 )
 
 var (
@@ -19,22 +24,21 @@ var (
 // Installs the given targets, for the target environment, to the given
 // destination.
 func (i *Installer) InstallTargets(installTarget minecraft.InstallTarget, dest string, targets []*modpacksch.Target) error {
+	return i.InstallTargetsContext(context.Background(), installTarget, dest, targets)
+}
+
+// See InstallTargets
+func (i *Installer) InstallTargetsContext(ctx context.Context, installTarget minecraft.InstallTarget, dest string, targets []*modpacksch.Target) error {
 	// Get the target Minecraft version for the pack
-	var mcVersion *minecraft.Version
-	for _, target := range targets {
-		if target.Type == "game" {
-			ver, err := minecraft.ParseVersion(target.Version)
-			if err != nil {
-				return err
-			}
-			mcVersion = ver
-			break
-		}
+	mcVersion, err := getGameVersion(targets)
+	if err != nil {
+		return err
 	}
 
-	// If we can't determine the game version, we can't really proceed
-	if mcVersion == nil {
-		return FailedToDetermineGameVersion
+	// Only one install may modify the launcher at a time
+	if installTarget == minecraft.Client {
+		i.launcherLock.Lock()
+		defer i.launcherLock.Unlock()
 	}
 
 	// Install mod loaders, etc
@@ -51,14 +55,175 @@ func (i *Installer) InstallTargets(installTarget minecraft.InstallTarget, dest s
 
 			// Minecraft Forge
 			if target.Name == "forge" {
-				if err := i.ForgeInstaller.InstallForge(installTarget, loaderDest, mcVersion, target.Version); err != nil {
+				if err := i.ForgeInstaller.InstallForgeContext(ctx, installTarget, loaderDest, mcVersion, target.Version); err != nil {
 					return err
 				}
+
+				// FML for Minecraft 1.3 and 1.4 loads libraries from the game
+				// directory, rather than the launcher's
+				if installTarget == minecraft.Client && forge.IsLegacy(mcVersion) {
+					if err := i.ForgeInstaller.InstallFMLLibrariesContext(ctx, dest, mcVersion); err != nil {
+						return err
+					}
+				}
+			}
+
+			// NeoForge
+			if target.Name == "neoforge" {
+				if err := i.ForgeInstaller.InstallNeoForgeContext(ctx, installTarget, loaderDest, mcVersion, target.Version); err != nil {
+					return err
+				}
+			}
+
+			// Fabric Loader
+			if target.Name == "fabric" {
+				if err := i.FabricInstaller.InstallFabricContext(ctx, installTarget, loaderDest, mcVersion, target.Version); err != nil {
+					return err
+				}
+			}
+
+			// Quilt Loader
+			if target.Name == "quilt" {
+				if err := i.QuiltInstaller.InstallQuiltContext(ctx, installTarget, loaderDest, mcVersion, target.Version); err != nil {
+					return err
+				}
+			}
+
+			// todo: other modloaders
+		}
+	}
+
+	// LiteLoader, chained onto any other modloader - so is installed once
+	// they have been
+	for _, target := range targets {
+		if target.Type == "modloader" && target.Name == "liteloader" {
+			var loaderDest string
+			if installTarget == minecraft.Client {
+				loaderDest = launcher.GetLauncherDir()
+			} else {
+				loaderDest = dest
 			}
 
-			// todo: liteloader, fabric, etc support?
+			parent := getModLoaderVersion(mcVersion, targets)
+			if err := i.LiteLoaderInstaller.InstallLiteLoaderContext(ctx, installTarget, loaderDest, mcVersion, target.Version, parent); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
+
+// Gets the target Minecraft version from the given targets.
+func getGameVersion(targets []*modpacksch.Target) (*minecraft.Version, error) {
+	for _, target := range targets {
+		if target.Type == "game" {
+			return minecraft.ParseVersion(target.Version)
+		}
+	}
+
+	// If we can't determine the game version, we can't really proceed
+	return nil, FailedToDetermineGameVersion
+}
+
+// Gets the launcher version to use for the profile of a pack with the
+// given targets, based on the modloader in use.
+func getProfileVersion(mcVersion *minecraft.Version, targets []*modpacksch.Target) string {
+	version := getModLoaderVersion(mcVersion, targets)
+
+	// LiteLoader, chained onto any other modloader
+	for _, target := range targets {
+		if target.Type == "modloader" && target.Name == "liteloader" {
+			return liteloader.VersionName(mcVersion, version)
+		}
+	}
+
+	return version
+}
+
+// Gets the launcher version of the modloader in use by a pack with the
+// given targets, leaving out LiteLoader.
+func getModLoaderVersion(mcVersion *minecraft.Version, targets []*modpacksch.Target) string {
+	for _, target := range targets {
+		if target.Type == "modloader" {
+			// Minecraft Forge
+			if target.Name == "forge" {
+				// Minecraft 1.13 and above
+				if mcVersion.Major >= 1 && mcVersion.Minor >= 13 {
+					return mcVersion.String() + "-forge-" + target.Version
+				}
+				return mcVersion.String() + "-forge" + mcVersion.String() + "-" + target.Version
+			}
+
+			// NeoForge
+			if target.Name == "neoforge" {
+				return forge.NeoForgeVersionName(mcVersion, target.Version)
+			}
+
+			// Fabric Loader
+			if target.Name == "fabric" {
+				return fabric.VersionName(mcVersion, target.Version)
+			}
+
+			// Quilt Loader
+			if target.Name == "quilt" {
+				return quilt.VersionName(mcVersion, target.Version)
+			}
+
+			// todo: other modloaders
+		}
+	}
+
+	return ""
+}
+
+// Gets the jar that a server of a pack with the given targets is launched
+// with, based on the modloader in use. Should the server be launched some
+// other way, or the modloader not be known, no jar is given.
+func getServerJar(mcVersion *minecraft.Version, targets []*modpacksch.Target) (string, error) {
+	for _, target := range targets {
+		if target.Type == "modloader" {
+			// Minecraft Forge
+			if target.Name == "forge" {
+				return forge.ServerJar(mcVersion, target.Version)
+			}
+
+			// NeoForge, always launched with its run scripts
+			if target.Name == "neoforge" {
+				return "", nil
+			}
+
+			// Fabric Loader
+			if target.Name == "fabric" {
+				return fabric.ServerLaunchJar, nil
+			}
+
+			// Quilt Loader
+			if target.Name == "quilt" {
+				return quilt.ServerLaunchJar, nil
+			}
+
+			// todo: other modloaders
+		}
+	}
+
+	return "", nil
+}
+
+// Determines whether a server of a pack with the given targets is launched
+// with the run scripts created by its modloader's installer.
+func usesRunScripts(mcVersion *minecraft.Version, targets []*modpacksch.Target) bool {
+	for _, target := range targets {
+		// Minecraft Forge, for Minecraft 1.17 and above
+		if target.Type == "modloader" && target.Name == "forge" {
+			return mcVersion.Major >= 1 && mcVersion.Minor >= 17
+		}
+
+		// NeoForge
+		if target.Type == "modloader" && target.Name == "neoforge" {
+			return true
+		}
+	}
+
+	return false
+}
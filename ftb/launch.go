@@ -0,0 +1,141 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	startScript = "start.sh"
+	startBatch  = "start.bat"
+
+	// Read by the run scripts that the Forge installer creates, for
+	// Minecraft 1.17 and above
+	userJvmArgs = "user_jvm_args.txt"
+)
+
+// LaunchFile is a file of a server's launch configuration, such as its
+// start script, generated by the installer.
+type LaunchFile struct {
+	Name     string
+	Contents string
+	Mode     os.FileMode
+
+	// Set should the file have been modified since it was generated, or be
+	// protected by a rule - in which case it is left as it is.
+	Keep bool
+}
+
+// Gets the launch configuration for a server of a pack with the given
+// targets, using the given memory (should it be known).
+func getLaunchConfig(pack *modpacksch.Pack, mcVersion *minecraft.Version, targets []*modpacksch.Target, memory *Memory) ([]*LaunchFile, error) {
+	var args []string
+	if memory != nil {
+		args = memory.JavaArgs()
+	}
+	header := "Generated by ftbinstall for " + pack.Name + ", this is left alone once modified."
+
+	jar, err := getServerJar(mcVersion, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Forge installer creates run scripts, which read their arguments
+	// from a file
+	if jar == "" {
+		if !usesRunScripts(mcVersion, targets) || len(args) == 0 {
+			return nil, nil
+		}
+		return []*LaunchFile{
+			{
+				Name:     userJvmArgs,
+				Contents: "# " + header + "\n" + strings.Join(args, "\n") + "\n",
+				Mode:     0644,
+			},
+		}, nil
+	}
+
+	command := strings.Join(append(append([]string{"java"}, args...), "-jar", jar, "nogui"), " ")
+	return []*LaunchFile{
+		{
+			Name:     startScript,
+			Contents: "#!/bin/sh\n# " + header + "\n" + command + "\n",
+			Mode:     0755,
+		},
+		{
+			Name:     startBatch,
+			Contents: "@echo off\r\nrem " + header + "\r\n" + command + "\r\npause\r\n",
+			Mode:     0644,
+		},
+	}, nil
+}
+
+// Works out which of the given launch files can be written, keeping any
+// that have been modified since they were last generated, or are protected
+// by one of the given rules.
+func planLaunchConfig(dest string, settings *InstallSettings, files []*LaunchFile, rules []*ProtectRule) error {
+	for _, file := range files {
+		if matchProtectRules(rules, file.Name) != nil {
+			file.Keep = true
+			continue
+		}
+
+		hash, err := util.HashFile(filepath.Join(dest, file.Name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		file.Keep = hash != settings.LaunchConfig[file.Name] && hash != hashString(file.Contents)
+	}
+	return nil
+}
+
+// Stages the given launch files, returning the hashes to record for them.
+func writeLaunchConfig(tx *transaction, settings *InstallSettings, files []*LaunchFile) (map[string]string, error) {
+	hashes := map[string]string{}
+	for _, file := range files {
+		if file.Keep {
+			// So the file continues to be seen as modified
+			if hash, ok := settings.LaunchConfig[file.Name]; ok {
+				hashes[file.Name] = hash
+			}
+			continue
+		}
+
+		path := filepath.Join(tx.dest, file.Name)
+		stagingPath, err := tx.stagingPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(stagingPath), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(stagingPath, []byte(file.Contents), file.Mode); err != nil {
+			return nil, err
+		}
+		if err := tx.write(path); err != nil {
+			return nil, err
+		}
+		hashes[file.Name] = hashString(file.Contents)
+	}
+	return hashes, nil
+}
+
+func hashString(s string) string {
+	hash := sha1.Sum([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,114 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+func TestGetMemory(t *testing.T) {
+	if memory := getMemory(nil); memory != nil {
+		t.Errorf("expected no memory without specs, got %v", memory)
+	}
+
+	memory := getMemory(&modpacksch.Specs{Minimum: 4096, Recommended: 6144})
+	args := strings.Join(memory.JavaArgs(), " ")
+	if args != "-Xms4096M -Xmx6144M" {
+		t.Errorf("unexpected java args %s", args)
+	}
+
+	// The recommended memory is never less than the minimum
+	memory = getMemory(&modpacksch.Specs{Minimum: 4096})
+	if memory.Recommended != 4096 {
+		t.Errorf("expected 4096M recommended, got %d", memory.Recommended)
+	}
+
+	memory.Available = 2048
+	if !memory.LowMemory() {
+		t.Error("2048M should be less than the minimum")
+	}
+	memory.Available = 0
+	if memory.LowMemory() {
+		t.Error("unknown memory should be assumed to be enough")
+	}
+}
+
+func TestGetLaunchConfig(t *testing.T) {
+	pack := &modpacksch.Pack{Name: "Example"}
+	memory := &Memory{Minimum: 4096, Recommended: 6144}
+
+	// Launched with start scripts
+	mcVersion, _ := minecraft.ParseVersion("1.12.2")
+	targets := []*modpacksch.Target{
+		{Name: "minecraft", Type: "game", Version: "1.12.2"},
+		{Name: "forge", Type: "modloader", Version: "14.23.5.2860"},
+	}
+	files, err := getLaunchConfig(pack, mcVersion, targets, memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Name != startScript || files[1].Name != startBatch {
+		t.Fatalf("expected start scripts, got %v", files)
+	}
+	if !strings.Contains(files[0].Contents, "java -Xms4096M -Xmx6144M -jar forge-1.12.2-14.23.5.2860.jar nogui") {
+		t.Errorf("unexpected start script %q", files[0].Contents)
+	}
+
+	// Launched with Forge's run scripts
+	mcVersion, _ = minecraft.ParseVersion("1.18.2")
+	targets = []*modpacksch.Target{
+		{Name: "minecraft", Type: "game", Version: "1.18.2"},
+		{Name: "forge", Type: "modloader", Version: "40.1.0"},
+	}
+	files, err = getLaunchConfig(pack, mcVersion, targets, memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != userJvmArgs || !strings.HasSuffix(files[0].Contents, "-Xms4096M\n-Xmx6144M\n") {
+		t.Errorf("expected jvm arguments, got %v", files)
+	}
+}
+
+func TestPlanLaunchConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ftblaunch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, startScript), []byte("java -jar server.jar"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, startBatch), []byte("previously generated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	settings := &InstallSettings{
+		LaunchConfig: map[string]string{
+			startBatch: hashString("previously generated"),
+		},
+	}
+
+	files := []*LaunchFile{
+		{Name: startScript, Contents: "java -Xmx4096M -jar server.jar"},
+		{Name: startBatch, Contents: "java -Xmx4096M -jar server.jar"},
+		{Name: userJvmArgs, Contents: "-Xmx4096M"},
+	}
+	if err := planLaunchConfig(dir, settings, files, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !files[0].Keep {
+		t.Error("the modified start script should be kept")
+	}
+	if files[1].Keep || files[2].Keep {
+		t.Error("unmodified, and missing, files should be written")
+	}
+}
@@ -0,0 +1,77 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"fmt"
+	"strconv"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+// Memory describes the memory, in megabytes, that a pack version should be
+// given - as published by modpacks.ch.
+type Memory struct {
+	Minimum     int
+	Recommended int
+
+	// The total memory of this machine, or 0 should it not be known
+	Available int
+}
+
+// Gets the memory that a pack version with the given specs should be
+// given, or nil should the pack not publish any.
+func getMemory(specs *modpacksch.Specs) *Memory {
+	if specs == nil || (specs.Minimum <= 0 && specs.Recommended <= 0) {
+		return nil
+	}
+
+	memory := &Memory{
+		Minimum:     specs.Minimum,
+		Recommended: specs.Recommended,
+	}
+	if memory.Minimum <= 0 {
+		memory.Minimum = memory.Recommended
+	}
+	if memory.Recommended < memory.Minimum {
+		memory.Recommended = memory.Minimum
+	}
+	if total, err := util.TotalMemory(); err == nil {
+		memory.Available = int(total / (1024 * 1024))
+	}
+	return memory
+}
+
+// JavaArgs gets the arguments to give Java, starting with the minimum
+// memory and allowing up to the recommended memory.
+func (m *Memory) JavaArgs() []string {
+	return []string{
+		"-Xms" + strconv.Itoa(m.Minimum) + "M",
+		"-Xmx" + strconv.Itoa(m.Recommended) + "M",
+	}
+}
+
+// LowMemory determines whether this machine has less memory than the
+// minimum. Should the memory of this machine not be known, it is assumed
+// to be enough.
+func (m *Memory) LowMemory() bool {
+	return m.Available > 0 && m.Available < m.Minimum
+}
+
+func (m *Memory) String() string {
+	return fmt.Sprintf("%dM minimum, %dM recommended", m.Minimum, m.Recommended)
+}
+
+// Warns should this machine have less memory than the pack needs.
+func printLowMemoryWarning(memory *Memory) {
+	if memory == nil || !memory.LowMemory() {
+		return
+	}
+	fmt.Println("************************************************************************************************")
+	fmt.Printf("This pack needs at least %dM of memory, but this machine only has %dM.\n", memory.Minimum, memory.Available)
+	fmt.Println("The pack is likely to run poorly, or not at all!")
+	fmt.Println("************************************************************************************************")
+}
@@ -0,0 +1,222 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	// The directory, within DataDir, that the original contents of
+	// mergeable files are kept - named by their sha1 hash.
+	pristineDir = "pristine"
+)
+
+// Whether the file at the given path is a text config, that can be merged
+// should both the player and the pack change it.
+func isMergeable(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cfg", ".toml", ".json", ".properties":
+		return true
+	default:
+		return false
+	}
+}
+
+// Gets the path that the original contents of a file, with the given
+// hash, are kept.
+func (i *Installer) pristinePath(dest string, hash string) string {
+	return filepath.Join(dest, i.DataDir, pristineDir, hash)
+}
+
+// Whether the original contents of a file, with the given hash, are kept.
+func (i *Installer) hasPristine(dest string, hash string) bool {
+	_, err := os.Stat(i.pristinePath(dest, hash))
+	return err == nil
+}
+
+// Keeps the original contents of the given file, so it can later be used
+// as the base of a merge.
+func (i *Installer) storePristine(dest string, hash string, contents []byte) error {
+	path := i.pristinePath(dest, hash)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, contents, 0644)
+}
+
+// Keeps the original contents of the file at the given path, should it be
+// mergeable.
+func (i *Installer) storePristineFile(dest string, hash string, path string) error {
+	if !isMergeable(path) || i.hasPristine(dest, hash) {
+		return nil
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return i.storePristine(dest, hash, contents)
+}
+
+// Removes the original contents of any files that are no longer a part of
+// the install.
+func (i *Installer) prunePristine(dest string, files map[string]string) error {
+	hashes := map[string]bool{}
+	for _, hash := range files {
+		hashes[hash] = true
+	}
+
+	dir := filepath.Join(dest, i.DataDir, pristineDir)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, info := range infos {
+		if !hashes[info.Name()] {
+			if err := os.Remove(filepath.Join(dir, info.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Merges the changes the player has made to the given file, with those
+// made by the pack. Should the merge not be clean, the merge (with
+// conflict markers) is written under DataDir/<version> and the player's
+// file is left alone.
+// The hash to record for the file is returned.
+func (i *Installer) mergeFile(ctx context.Context, install *Install, file *PlannedFile, tx *transaction) (string, string, error) {
+	// GET the pack's copy
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, file.File.URL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Accept", "*")
+	theirs, err := util.DownloadBytes(req, file.File.Sha1, int64(file.File.Size))
+	if err != nil {
+		return "", "", err
+	}
+	hasher := sha1.New()
+	hasher.Write(theirs)
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if err := i.storePristine(install.dest, hash, theirs); err != nil {
+		return "", "", err
+	}
+
+	base, err := ioutil.ReadFile(i.pristinePath(install.dest, file.OriginalHash))
+	if err != nil {
+		return "", "", err
+	}
+	ours, err := ioutil.ReadFile(file.Dest)
+	if err != nil {
+		return "", "", err
+	}
+
+	lines, conflicts, err := util.MergeLines(
+		strings.Split(string(base), "\n"),
+		strings.Split(string(ours), "\n"),
+		strings.Split(string(theirs), "\n"),
+		"yours", "modpack v"+strconv.Itoa(install.Version),
+	)
+	if err != nil {
+		return "", "", err
+	}
+	merged := []byte(strings.Join(lines, "\n"))
+
+	// A clean merge of JSON can still produce invalid JSON
+	if conflicts == 0 && strings.ToLower(filepath.Ext(file.Path)) == ".json" && !json.Valid(merged) {
+		conflicts = 1
+	}
+
+	if conflicts == 0 {
+		if err := i.writeFile(file.Dest, merged, tx); err != nil {
+			return "", "", err
+		}
+
+		install.mu.Lock()
+		install.merged = append(install.merged, file.Path)
+		install.mu.Unlock()
+
+		return fmt.Sprintf("Merged changes to '%s'", file.Path), hash, nil
+	}
+
+	// Keep the players copy in place, the player will have to resolve the
+	// conflicts themselves
+	divertDest := i.divertPath(install.dest, install.Version, file.File)
+	if err := i.writeFile(divertDest, merged, tx); err != nil {
+		return "", "", err
+	}
+
+	install.mu.Lock()
+	install.conflicts = append(install.conflicts, file.Path)
+	install.mu.Unlock()
+
+	// As the player's copy hasn't changed, continue to merge from the
+	// original
+	return fmt.Sprintf("Failed to merge changes to '%s'", file.Path), file.OriginalHash, nil
+}
+
+// Writes the given contents to the given path, staging it should a
+// transaction be given.
+func (i *Installer) writeFile(path string, contents []byte, tx *transaction) error {
+	fileDest := path
+	if tx != nil {
+		stagingPath, err := tx.stagingPath(path)
+		if err != nil {
+			return err
+		}
+		fileDest = stagingPath
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fileDest), os.ModePerm); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(fileDest, contents, 0644); err != nil {
+		return err
+	}
+
+	if tx != nil {
+		return tx.write(path)
+	}
+	return nil
+}
+
+// Prints a report of the files that were merged during the install.
+func (i *Installer) printMergeReport(install *Install) {
+	if len(install.merged) > 0 {
+		fmt.Println("The following files had been modified, and have had the modpack's changes merged in:")
+		for _, path := range install.merged {
+			fmt.Printf("\t%s\n", path)
+		}
+	}
+
+	if len(install.conflicts) > 0 {
+		fmt.Println("************************************************************************************************")
+		fmt.Println("The following files had been modified, and the modpack's changes could not be merged in")
+		fmt.Println("automatically. Your copies have been left in place, and the merges (with conflict markers)")
+		fmt.Printf("have been written under %s/%d.\n", i.DataDir, install.Version)
+		for _, path := range install.conflicts {
+			fmt.Printf("\t%s\n", path)
+		}
+		fmt.Println("Please resolve the conflicts before playing!")
+		fmt.Println("************************************************************************************************")
+	}
+}
@@ -0,0 +1,403 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/google/uuid"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/minecraft/launcher"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+var (
+	ErrPathOutsideInstall = errors.New("ftb: install.json records a path outside of the install")
+)
+
+// FileAction describes what an install will do with a file.
+type FileAction int
+
+const (
+	// The file will be downloaded to its place in the pack.
+	DownloadFile FileAction = iota
+
+	// The file is already installed, as its sha1 hash matches.
+	SkipFile
+
+	// The player has modified the file, so the pack's copy will be
+	// downloaded to DataDir/<version> instead.
+	DivertFile
+
+	// The file is no longer a part of the pack, and will be deleted.
+	DeleteFile
+
+	// The file is no longer a part of the pack, but the player has
+	// modified it - so it will be left in place.
+	KeepFile
+
+	// The player has modified the file, so the pack's changes will be
+	// merged into the player's copy. Should the merge not be clean, it
+	// will be written to DataDir/<version> instead.
+	MergeFile
+
+	// The file is protected by a rule, so will be left as it is.
+	ProtectFile
+)
+
+func (a FileAction) String() string {
+	switch a {
+	case DownloadFile:
+		return "download"
+	case SkipFile:
+		return "skip"
+	case DivertFile:
+		return "divert"
+	case DeleteFile:
+		return "delete"
+	case KeepFile:
+		return "keep"
+	case MergeFile:
+		return "merge"
+	case ProtectFile:
+		return "protect"
+	default:
+		return "unknown"
+	}
+}
+
+// PlannedFile describes what an install will do with a single file.
+type PlannedFile struct {
+	// The path of the file within the pack, for example
+	// ./mods/example.jar
+	Path string
+
+	// Where on disk the file will be written to, or removed from.
+	Dest string
+
+	Action FileAction
+
+	// The pack's file, nil should the file no longer be a part of the
+	// pack.
+	File *modpacksch.File
+
+	// The sha1 hash of the file currently on disk, if any.
+	Hash string
+
+	// The sha1 hash recorded for the file by the previous install, if
+	// any.
+	OriginalHash string
+
+	// The rule protecting the file, for protected files.
+	Rule *ProtectRule
+}
+
+// PlannedModLoader describes a modloader that an install will install.
+type PlannedModLoader struct {
+	Name    string
+	Version string
+}
+
+// Plan describes everything an install of a pack version will do,
+// without having touched the disk.
+type Plan struct {
+	Pack        *modpacksch.Pack
+	Version     *modpacksch.PackVersion
+	Target      minecraft.InstallTarget
+	Destination string
+
+	// The install settings that will be updated by the install - should
+	// this be a fresh install, these will be newly created.
+	Settings *InstallSettings
+	Fresh    bool
+
+	GameVersion *minecraft.Version
+	ModLoaders  []*PlannedModLoader
+	Files       []*PlannedFile
+
+	// The profile that will be installed to the Minecraft launcher, nil
+	// for server installs. Note that the icon isn't fetched until the
+	// plan is installed.
+	Profile *launcher.Profile
+
+	// The memory the pack should be given, nil should the pack not publish
+	// any specs.
+	Memory *Memory
+
+	// The launch configuration that will be generated, for server installs
+	LaunchFiles []*LaunchFile
+}
+
+// FilesFor gets the planned files that have the given action.
+func (p *Plan) FilesFor(action FileAction) []*PlannedFile {
+	var files []*PlannedFile
+	for _, file := range p.Files {
+		if file.Action == action {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// PlanPackVersion computes exactly what InstallPackVersion would do for
+// the given pack version, without making any changes to the disk.
+func (i *Installer) PlanPackVersion(installTarget minecraft.InstallTarget, dest string, pack *modpacksch.Pack, version *modpacksch.PackVersion) (*Plan, error) {
+	return i.planPackVersion(context.Background(), installTarget, dest, pack, version)
+}
+
+func (i *Installer) planPackVersion(ctx context.Context, installTarget minecraft.InstallTarget, dest string, pack *modpacksch.Pack, version *modpacksch.PackVersion) (*Plan, error) {
+	destination, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Pack:        pack,
+		Version:     version,
+		Target:      installTarget,
+		Destination: destination,
+	}
+
+	// Find existing install (or create one)
+	if readJson(filepath.Join(destination, i.DataDir, settingsFile), &plan.Settings) != nil {
+		plan.Settings = &InstallSettings{
+			ID:      uuid.New().String(),
+			Pack:    pack.ID,
+			Version: version.ID,
+			Target:  installTarget,
+			Files:   map[string]string{},
+		}
+		plan.Fresh = true
+	} else if pack.ID != plan.Settings.Pack {
+		return nil, OtherPackAlreadyInstalled
+	}
+
+	// Get the target Minecraft version for the pack
+	plan.GameVersion, err = getGameVersion(version.Targets)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range version.Targets {
+		if target.Type == "modloader" {
+			plan.ModLoaders = append(plan.ModLoaders, &PlannedModLoader{
+				Name:    target.Name,
+				Version: target.Version,
+			})
+		}
+	}
+
+	// Work out what to do with each file
+	rules, err := i.loadProtectRules(destination)
+	if err != nil {
+		return nil, err
+	}
+	install := &Install{
+		Version:       version.ID,
+		OriginalFiles: plan.Settings.knownFiles(),
+	}
+	plan.Files, err = i.planFiles(ctx, install, installTarget, destination, version.Files, rules)
+	if err != nil {
+		return nil, err
+	}
+	removals, err := i.planRemovals(ctx, install, destination, plan.Files, rules)
+	if err != nil {
+		return nil, err
+	}
+	plan.Files = append(plan.Files, removals...)
+
+	// Create profile for the Minecraft launcher
+	plan.Memory = getMemory(version.Specs)
+	if installTarget == minecraft.Client {
+		plan.Profile = &launcher.Profile{
+			Name:    pack.Name + " " + version.Name,
+			Type:    "custom",
+			GameDir: destination,
+			Version: getProfileVersion(plan.GameVersion, version.Targets),
+		}
+		if plan.Memory != nil {
+			plan.Profile.JavaArgs = strings.Join(plan.Memory.JavaArgs(), " ")
+		}
+	} else {
+		plan.LaunchFiles, err = getLaunchConfig(pack, plan.GameVersion, version.Targets, plan.Memory)
+		if err != nil {
+			return nil, err
+		}
+		if err := planLaunchConfig(destination, plan.Settings, plan.LaunchFiles, rules); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// Works out what to do with each of the given files, for the target
+// environment.
+// Files that already exist, and are protected by one of the given rules,
+// are left as they are.
+func (i *Installer) planFiles(ctx context.Context, install *Install, target minecraft.InstallTarget, dest string, files []*modpacksch.File, rules []*ProtectRule) ([]*PlannedFile, error) {
+	var planned []*PlannedFile
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Ignore files for another target
+		if (target == minecraft.Client && file.ServerOnly) || (target == minecraft.Server && file.ClientOnly) {
+			continue
+		}
+
+		plannedFile := &PlannedFile{
+			Path:         file.Path + file.Name,
+			Dest:         filepath.Join(dest, filepath.FromSlash(file.Path), file.Name),
+			Action:       DownloadFile,
+			File:         file,
+			OriginalHash: install.OriginalFiles[file.Path+file.Name],
+		}
+		planned = append(planned, plannedFile)
+
+		// If file already exists, check the checksum
+		if _, err := os.Stat(plannedFile.Dest); err != nil {
+			// A protected file the player has deleted stays deleted -
+			// whereas one we've never installed is downloaded as normal
+			if plannedFile.OriginalHash != "" {
+				if rule := matchProtectRules(rules, filepath.FromSlash(plannedFile.Path)); rule != nil {
+					plannedFile.Action = ProtectFile
+					plannedFile.Rule = rule
+				}
+			}
+			continue
+		}
+		hash, err := util.HashFile(plannedFile.Dest)
+		if err != nil {
+			return nil, err
+		}
+		plannedFile.Hash = hash
+
+		// If already exists, there's nothing to do
+		if hash == file.Sha1 {
+			plannedFile.Action = SkipFile
+			continue
+		}
+
+		// Don't touch the file if it's protected
+		if rule := matchProtectRules(rules, filepath.FromSlash(plannedFile.Path)); rule != nil {
+			plannedFile.Action = ProtectFile
+			plannedFile.Rule = rule
+			continue
+		}
+
+		// If the file previously existed, don't override if the player made changes
+		if plannedFile.OriginalHash != "" && hash != plannedFile.OriginalHash {
+			// Configs can be merged, so long as we kept the original
+			if isMergeable(file.Name) && i.hasPristine(dest, plannedFile.OriginalHash) {
+				plannedFile.Action = MergeFile
+				continue
+			}
+
+			plannedFile.Action = DivertFile
+			plannedFile.Dest = i.divertPath(dest, install.Version, file)
+		}
+	}
+
+	return planned, nil
+}
+
+// Gets the path that the given file will be installed to, should the
+// player have modified their copy.
+func (i *Installer) divertPath(dest string, version int, file *modpacksch.File) string {
+	return filepath.Join(dest, i.DataDir, strconv.Itoa(version), filepath.FromSlash(file.Path), file.Name)
+}
+
+// Resolves the given path, as recorded in install.json, to where it is on
+// disk within the install - along with its path relative to the install.
+// Paths that are absolute, or would escape the install, are rejected.
+func resolveInstallPath(dest string, path string) (string, string, error) {
+	if filepath.IsAbs(filepath.FromSlash(path)) || strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("%w: %s", ErrPathOutsideInstall, path)
+	}
+	fileDest := filepath.Join(dest, filepath.FromSlash(path))
+	relPath, err := filepath.Rel(dest, fileDest)
+	if err != nil {
+		return "", "", err
+	}
+	if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) || filepath.IsAbs(relPath) {
+		return "", "", fmt.Errorf("%w: %s", ErrPathOutsideInstall, path)
+	}
+	return fileDest, relPath, nil
+}
+
+// Works out which files from the previous install are no longer a part
+// of the pack, and whether they can be removed.
+func (i *Installer) planRemovals(ctx context.Context, install *Install, dest string, files []*PlannedFile, rules []*ProtectRule) ([]*PlannedFile, error) {
+	current := map[string]bool{}
+	for _, file := range files {
+		current[file.Path] = true
+	}
+
+	var paths []string
+	for path := range install.OriginalFiles {
+		if !current[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var planned []*PlannedFile
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fileDest, relPath, err := resolveInstallPath(dest, path)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(fileDest)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		// While this should never be an issue anyway - for peace of mind,
+		// ftbinstall will not delete ANYTHING that is protected.
+		if rule := matchProtectRules(rules, relPath); rule != nil {
+			planned = append(planned, &PlannedFile{
+				Path:         path,
+				Dest:         fileDest,
+				Action:       ProtectFile,
+				OriginalHash: install.OriginalFiles[path],
+				Rule:         rule,
+			})
+			continue
+		}
+
+		hash, err := util.HashFile(fileDest)
+		if err != nil {
+			return nil, err
+		}
+
+		plannedFile := &PlannedFile{
+			Path:         path,
+			Dest:         fileDest,
+			Action:       DeleteFile,
+			Hash:         hash,
+			OriginalHash: install.OriginalFiles[path],
+		}
+		if hash != plannedFile.OriginalHash {
+			plannedFile.Action = KeepFile
+		}
+		planned = append(planned, plannedFile)
+	}
+
+	return planned, nil
+}
@@ -0,0 +1,135 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+const (
+	helloSha1 = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d" // hello
+	worldSha1 = "7c211433f02071597741e6ff5a8ea34789abbf43" // world
+)
+
+func TestPlanPackVersion(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbplan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	installer := NewInstaller(1)
+	writeTestFile(t, dest, "mods/same.jar", "hello")
+	writeTestFile(t, dest, "config/modified.cfg", "modified")
+	writeTestFile(t, dest, "mods/removed.jar", "hello")
+	writeTestFile(t, dest, "config/removed.cfg", "modified")
+	if err := os.MkdirAll(filepath.Join(dest, installer.DataDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJson(filepath.Join(dest, installer.DataDir, settingsFile), &InstallSettings{
+		ID:      "test",
+		Pack:    1,
+		Version: 1,
+		Files: map[string]string{
+			"./mods/same.jar":       helloSha1,
+			"./config/modified.cfg": helloSha1,
+			"./mods/removed.jar":    helloSha1,
+			"./config/removed.cfg":  helloSha1,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := &modpacksch.Pack{ID: 1, Name: "Test"}
+	version := &modpacksch.PackVersion{
+		ID:   2,
+		Name: "1.1.0",
+		Targets: []*modpacksch.Target{
+			{Type: "game", Name: "minecraft", Version: "1.12.2"},
+			{Type: "modloader", Name: "forge", Version: "14.23.5.2855"},
+		},
+		Files: []*modpacksch.File{
+			{Path: "./mods/", Name: "same.jar", Sha1: helloSha1},
+			{Path: "./mods/", Name: "new.jar", Sha1: worldSha1},
+			{Path: "./config/", Name: "modified.cfg", Sha1: worldSha1},
+			{Path: "./mods/", Name: "server.jar", Sha1: worldSha1, ServerOnly: true},
+		},
+	}
+
+	plan, err := installer.PlanPackVersion(minecraft.Client, dest, pack, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Fresh {
+		t.Error("plan should update the existing install")
+	}
+
+	expected := map[string]FileAction{
+		"./mods/same.jar":       SkipFile,
+		"./mods/new.jar":        DownloadFile,
+		"./config/modified.cfg": DivertFile,
+		"./mods/removed.jar":    DeleteFile,
+		"./config/removed.cfg":  KeepFile,
+	}
+	if len(plan.Files) != len(expected) {
+		t.Errorf("plan has %d files, should have %d", len(plan.Files), len(expected))
+	}
+	for _, file := range plan.Files {
+		if file.Action != expected[file.Path] {
+			t.Errorf("%s will %s, should %s", file.Path, file.Action, expected[file.Path])
+		}
+	}
+
+	if plan.Profile == nil || plan.Profile.Version != "1.12.2-forge1.12.2-14.23.5.2855" {
+		t.Errorf("unexpected profile: %+v", plan.Profile)
+	}
+}
+
+func TestPlanRemovalsOutsideInstall(t *testing.T) {
+	root, err := ioutil.TempDir("", "ftbplan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// A file outside of the install, that install.json claims
+	dest := filepath.Join(root, "instance")
+	writeTestFile(t, root, "outside.txt", "hello")
+
+	installer := NewInstaller(1)
+	for _, path := range []string{"../outside.txt", "./mods/../../outside.txt", filepath.ToSlash(filepath.Join(root, "outside.txt"))} {
+		install := &Install{
+			OriginalFiles: map[string]string{
+				path: helloSha1,
+			},
+		}
+		planned, err := installer.planRemovals(context.Background(), install, dest, nil, nil)
+		if !errors.Is(err, ErrPathOutsideInstall) {
+			t.Errorf("%s should have been rejected, got %v", path, err)
+		}
+		if len(planned) != 0 {
+			t.Errorf("%s shouldn't have been planned, got %+v", path, planned)
+		}
+	}
+	assertTestFile(t, root, "outside.txt", "hello")
+}
+
+func writeTestFile(t *testing.T, dest string, path string, contents string) {
+	path = filepath.Join(dest, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
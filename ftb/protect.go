@@ -0,0 +1,183 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The file, within an install, that players can use to protect files
+	// from being changed by updates.
+	ignoreFile = ".mcinstallignore"
+)
+
+// ProtectRule is a gitignore-style pattern, that protects the files it
+// matches from being changed, in any capacity, when updating modpacks.
+// Patterns beginning with ! un-protect the files they match, should an
+// earlier rule have protected them.
+type ProtectRule struct {
+	// The pattern, as it was written
+	Pattern string
+
+	// Where the rule came from, for example .mcinstallignore:3
+	Source string
+
+	negate   bool
+	dirOnly  bool
+	segments []string
+}
+
+// ParseProtectRule parses the given gitignore-style pattern.
+// Should the pattern be blank or a comment, nil is returned.
+func ParseProtectRule(pattern string, source string) *ProtectRule {
+	rule := &ProtectRule{
+		Pattern: pattern,
+		Source:  source,
+	}
+
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return nil
+	}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\`) {
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return nil
+	}
+
+	// Patterns without a slash match at any depth, otherwise they are
+	// relative to the root of the install
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	rule.segments = strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+
+	return rule
+}
+
+func (r *ProtectRule) String() string {
+	return "'" + r.Pattern + "' (" + r.Source + ")"
+}
+
+// Whether the rule matches the given slash-separated path.
+func (r *ProtectRule) matches(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return matchSegments(r.segments, strings.Split(relPath, "/"))
+}
+
+func matchSegments(pattern []string, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	// ** matches zero or more directories, though a trailing ** only
+	// matches what is within the directory
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return len(parts) > 0
+		}
+		for j := 0; j <= len(parts); j++ {
+			if matchSegments(pattern[1:], parts[j:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], parts[1:])
+}
+
+// Gets the rule protecting the given path (relative to the install), or
+// nil should it not be protected.
+// As with gitignore, the last rule to match a path wins - and files within
+// a protected directory can't be un-protected.
+func matchProtectRules(rules []*ProtectRule, relPath string) *ProtectRule {
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(relPath)), "/")
+
+	for j := range parts {
+		current := strings.Join(parts[:j+1], "/")
+		isDir := j < len(parts)-1
+
+		var matched *ProtectRule
+		for _, rule := range rules {
+			if rule.matches(current, isDir) {
+				matched = rule
+			}
+		}
+		if matched != nil && !matched.negate {
+			return matched
+		}
+	}
+
+	return nil
+}
+
+// Gets the rules protecting files within the install at the given
+// destination. These are any rules in the install's .mcinstallignore,
+// followed by the installer's Protect rules and then its ExcludedDirs and
+// data directory - which are last, so they can't be un-protected.
+func (i *Installer) loadProtectRules(dest string) ([]*ProtectRule, error) {
+	var rules []*ProtectRule
+
+	f, err := os.Open(filepath.Join(dest, ignoreFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		line := 0
+		for scanner.Scan() {
+			line++
+			if rule := ParseProtectRule(scanner.Text(), ignoreFile+":"+strconv.Itoa(line)); rule != nil {
+				rules = append(rules, rule)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, pattern := range i.Protect {
+		if rule := ParseProtectRule(pattern, "Protect"); rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+
+	return append(rules, i.excludedDirRules()...), nil
+}
+
+// Gets the rules protecting the installer's ExcludedDirs, and its data
+// directory.
+func (i *Installer) excludedDirRules() []*ProtectRule {
+	var rules []*ProtectRule
+	for _, dir := range i.ExcludedDirs {
+		rules = append(rules, ParseProtectRule("/"+dir+"/", "ExcludedDirs"))
+	}
+	return append(rules, ParseProtectRule("/"+i.DataDir+"/", "DataDir"))
+}
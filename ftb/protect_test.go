@@ -0,0 +1,189 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+func TestProtectRules(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbprotect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	writeTestFile(t, dest, ignoreFile, "# Keep my settings\noptions.txt\nconfig/journeymap/**\n!config/journeymap/default.cfg\n")
+
+	installer := NewInstaller(1)
+	installer.Protect = []string{"*.dat"}
+	rules, err := installer.loadProtectRules(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"options.txt":                   "options.txt",
+		"config/options.txt":            "options.txt",
+		"servers.dat":                   "*.dat",
+		"config/journeymap/client.cfg":  "config/journeymap/**",
+		"config/journeymap/default.cfg": "",
+		"config/journeymap.cfg":         "",
+		"saves/world/level.dat":         "/saves/",
+		"savesfile.txt":                 "",
+		".ftbinstall/install.json":      "/.ftbinstall/",
+		"mods/example.jar":              "",
+	}
+	for path, pattern := range expected {
+		rule := matchProtectRules(rules, path)
+		if pattern == "" {
+			if rule != nil {
+				t.Errorf("%s should not be protected, but was by %s", path, rule)
+			}
+		} else if rule == nil {
+			t.Errorf("%s should be protected by '%s'", path, pattern)
+		} else if rule.Pattern != pattern {
+			t.Errorf("%s should be protected by '%s', but was by %s", path, pattern, rule)
+		}
+	}
+}
+
+func TestProtectRulesExcludedDirs(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbprotect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	writeTestFile(t, dest, ignoreFile, "!saves/\n!/saves/**\n")
+
+	installer := NewInstaller(1)
+	installer.Protect = []string{"!saves/", "!.ftbinstall/"}
+	rules, err := installer.loadProtectRules(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ExcludedDirs, and the data directory, can't be un-protected
+	for path, pattern := range map[string]string{
+		"saves/world/level.dat":    "/saves/",
+		".ftbinstall/install.json": "/.ftbinstall/",
+	} {
+		if rule := matchProtectRules(rules, path); rule == nil || rule.Pattern != pattern {
+			t.Errorf("%s should be protected by '%s', but was by %v", path, pattern, rule)
+		}
+	}
+
+	for relPath, excluded := range map[string]bool{
+		"saves":         true,
+		"saves/world":   true,
+		".ftbinstall":   true,
+		"config":        false,
+		"savesfile.txt": false,
+	} {
+		if installer.IsExcludedDir(filepath.FromSlash(relPath)) != excluded {
+			t.Errorf("%s should be excluded: %t", relPath, excluded)
+		}
+	}
+}
+
+func TestPlanProtectedFiles(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbprotect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	writeTestFile(t, dest, "options.txt", "modified")
+	writeTestFile(t, dest, "servers.dat", "modified")
+
+	installer := NewInstaller(1)
+	installer.Protect = []string{"options.txt", "servers.dat"}
+
+	pack := &modpacksch.Pack{ID: 1, Name: "Test"}
+	version := &modpacksch.PackVersion{
+		ID:   1,
+		Name: "1.0.0",
+		Targets: []*modpacksch.Target{
+			{Type: "game", Name: "minecraft", Version: "1.12.2"},
+		},
+		Files: []*modpacksch.File{
+			{Path: "./", Name: "options.txt", Sha1: helloSha1},
+			{Path: "./", Name: "servers.dat", Sha1: helloSha1},
+		},
+	}
+
+	plan, err := installer.PlanPackVersion(minecraft.Server, dest, pack, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range plan.Files {
+		if file.Action != ProtectFile {
+			t.Errorf("%s should be protected, but will %s", file.Path, file.Action)
+		} else if file.Rule.Pattern != file.File.Name {
+			t.Errorf("%s should be protected by '%s', but is by %s", file.Path, file.File.Name, file.Rule)
+		}
+	}
+}
+
+func TestPlanProtectedDeletedFiles(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbprotect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	installer := NewInstaller(1)
+	installer.Protect = []string{"options.txt", "servers.dat"}
+
+	// options.txt was installed, and has since been deleted by the
+	// player - whereas servers.dat has never been installed
+	if err := os.MkdirAll(filepath.Join(dest, installer.DataDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJson(filepath.Join(dest, installer.DataDir, settingsFile), &InstallSettings{
+		ID:      "test",
+		Pack:    1,
+		Version: 1,
+		Files: map[string]string{
+			"./options.txt": helloSha1,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := &modpacksch.Pack{ID: 1, Name: "Test"}
+	version := &modpacksch.PackVersion{
+		ID:   2,
+		Name: "1.0.1",
+		Targets: []*modpacksch.Target{
+			{Type: "game", Name: "minecraft", Version: "1.12.2"},
+		},
+		Files: []*modpacksch.File{
+			{Path: "./", Name: "options.txt", Sha1: helloSha1},
+			{Path: "./", Name: "servers.dat", Sha1: helloSha1},
+		},
+	}
+
+	plan, err := installer.PlanPackVersion(minecraft.Server, dest, pack, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]FileAction{
+		"./options.txt": ProtectFile,
+		"./servers.dat": DownloadFile,
+	}
+	for _, file := range plan.Files {
+		if file.Action != expected[file.Path] {
+			t.Errorf("%s should %s, but will %s", file.Path, expected[file.Path], file.Action)
+		}
+	}
+}
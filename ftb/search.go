@@ -0,0 +1,63 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+var (
+	// The root of the modpacks.ch public API.
+	apiURL = "https://api.modpacks.ch/public/"
+)
+
+type searchResponse struct {
+	Packs []int `json:"packs"`
+	Total int   `json:"total"`
+	Limit int   `json:"limit"`
+
+	// Set should the search fail
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// SearchPacks searches modpacks.ch for packs matching the given term,
+// returning the IDs of (at most limit) matching packs.
+func SearchPacks(ctx context.Context, httpClient *http.Client, term string, limit int) ([]int, error) {
+	if httpClient == nil {
+		httpClient = util.HTTPClient
+	}
+
+	// Create the request
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, apiURL+"modpack/search/"+strconv.Itoa(limit)+"?term="+url.QueryEscape(term), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	// Make the request
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Get the matching packs
+	var response searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Status == "error" {
+		return nil, fmt.Errorf("ftb: search failed: %s", response.Message)
+	}
+	return response.Packs, nil
+}
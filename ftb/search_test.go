@@ -0,0 +1,42 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchPacks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/modpack/search/5" || r.URL.Query().Get("term") != "direwolf 20" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"status": "error", "message": "not found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"packs": [79, 80], "curseforge": [], "total": 2, "limit": 5}`)
+	}))
+	defer server.Close()
+
+	defer func(original string) {
+		apiURL = original
+	}(apiURL)
+	apiURL = server.URL + "/"
+
+	packs, err := SearchPacks(context.Background(), nil, "direwolf 20", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packs) != 2 || packs[0] != 79 || packs[1] != 80 {
+		t.Errorf("expected packs [79 80], got %v", packs)
+	}
+
+	if _, err := SearchPacks(context.Background(), nil, "direwolf 20", 10); err == nil {
+		t.Error("expected the search to fail")
+	}
+}
@@ -0,0 +1,363 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	transactionDir = "transaction"
+	journalFile    = "journal.json"
+)
+
+const (
+	// Files are still being staged, nothing in the install has been
+	// changed yet.
+	stagingState = "staging"
+
+	// Files are being moved into place, so any changes will need to be
+	// rolled back should the commit not complete.
+	committingState = "committing"
+)
+
+// A transaction stages the changes made by an install, so that they can
+// be committed all at once - and rolled back, should anything go wrong.
+//
+// Changes are staged under DataDir/transaction/staging, and any files
+// that are overwritten or deleted are moved to DataDir/transaction/backup
+// while committing. The journal records which files are being changed, so
+// an interrupted commit can be rolled back by the next install.
+//
+// Modloader installers write straight to the install, rather than staging
+// their changes, so the top-level files of the install can be snapshotted
+// to DataDir/transaction/snapshot beforehand - and are restored, should
+// the transaction be aborted or rolled back.
+type transaction struct {
+	dest string
+	dir  string
+
+	mu      sync.Mutex
+	journal *journal
+}
+
+type journal struct {
+	State   string          `json:"state"`
+	Entries []*journalEntry `json:"entries,omitempty"`
+
+	// Whether the top-level files of the install have been snapshotted,
+	// and the names of those files
+	Snapshotted bool     `json:"snapshotted,omitempty"`
+	Snapshot    []string `json:"snapshot,omitempty"`
+}
+
+type journalEntry struct {
+	// The path of the file, relative to the install destination
+	Path string `json:"path"`
+
+	// Whether the file is to be deleted, rather than written
+	Delete bool `json:"delete,omitempty"`
+
+	// Whether the file existed before the commit started
+	Existed bool `json:"existed,omitempty"`
+}
+
+// Begins a new transaction for the install at the given destination.
+func (i *Installer) beginTransaction(dest string) (*transaction, error) {
+	tx := &transaction{
+		dest: dest,
+		dir:  filepath.Join(dest, i.DataDir, transactionDir),
+		journal: &journal{
+			State: stagingState,
+		},
+	}
+
+	if err := os.RemoveAll(tx.dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(tx.dir, "staging"), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := tx.writeJournal(); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Recovers from an install that was interrupted, by rolling back any
+// partially committed changes.
+func (i *Installer) recoverTransaction(dest string) error {
+	tx := &transaction{
+		dest: dest,
+		dir:  filepath.Join(dest, i.DataDir, transactionDir),
+	}
+
+	if err := readJson(filepath.Join(tx.dir, journalFile), &tx.journal); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if tx.journal.State == committingState {
+		fmt.Println("A previous install was interrupted while committing changes, rolling back...")
+		return tx.rollback()
+	}
+	if tx.journal.Snapshotted {
+		fmt.Println("A previous install was interrupted while installing modloaders, restoring...")
+	}
+	return tx.abort()
+}
+
+// Snapshots the top-level files of the install, so that changes made to
+// them outside of the transaction can be undone.
+func (tx *transaction) snapshot() error {
+	infos, err := ioutil.ReadDir(tx.dest)
+	if err != nil {
+		return err
+	}
+
+	snapshotDir := filepath.Join(tx.dir, "snapshot")
+	if err := os.MkdirAll(snapshotDir, os.ModePerm); err != nil {
+		return err
+	}
+	var names []string
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if err := util.CopyFile(filepath.Join(tx.dest, info.Name()), filepath.Join(snapshotDir, info.Name())); err != nil {
+			return err
+		}
+		names = append(names, info.Name())
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.journal.Snapshotted = true
+	tx.journal.Snapshot = names
+	return tx.writeJournal()
+}
+
+// Restores the top-level files of the install to their snapshot, removing
+// any that have since been created.
+func (tx *transaction) restoreSnapshot() error {
+	if !tx.journal.Snapshotted {
+		return nil
+	}
+
+	snapshotDir := filepath.Join(tx.dir, "snapshot")
+	snapshotted := map[string]bool{}
+	for _, name := range tx.journal.Snapshot {
+		snapshotted[name] = true
+
+		// Leave unchanged files untouched
+		path := filepath.Join(tx.dest, name)
+		hash, err := util.HashFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		originalHash, err := util.HashFile(filepath.Join(snapshotDir, name))
+		if err != nil {
+			return err
+		}
+		if hash == originalHash {
+			continue
+		}
+		if err := util.CopyFile(filepath.Join(snapshotDir, name), path); err != nil {
+			return err
+		}
+	}
+
+	infos, err := ioutil.ReadDir(tx.dest)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if info.Mode().IsRegular() && !snapshotted[info.Name()] {
+			if err := os.Remove(filepath.Join(tx.dest, info.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Gets the path that the given file should be staged to.
+func (tx *transaction) stagingPath(path string) (string, error) {
+	relPath, err := filepath.Rel(tx.dest, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(tx.dir, "staging", relPath), nil
+}
+
+// Records that the given file has been staged, and should be written on
+// commit.
+func (tx *transaction) write(path string) error {
+	return tx.addEntry(path, false)
+}
+
+// Records that the given file should be deleted on commit.
+func (tx *transaction) remove(path string) error {
+	return tx.addEntry(path, true)
+}
+
+func (tx *transaction) addEntry(path string, delete bool) error {
+	relPath, err := filepath.Rel(tx.dest, path)
+	if err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	tx.journal.Entries = append(tx.journal.Entries, &journalEntry{
+		Path:   filepath.ToSlash(relPath),
+		Delete: delete,
+	})
+	return nil
+}
+
+// Commits the staged changes to the install, rolling back should any
+// change fail.
+func (tx *transaction) commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	// Record what existed before any changes are made, so we know what
+	// to restore
+	for _, entry := range tx.journal.Entries {
+		_, err := os.Lstat(tx.path(entry))
+		entry.Existed = err == nil
+	}
+	tx.journal.State = committingState
+	if err := tx.writeJournal(); err != nil {
+		return err
+	}
+
+	for _, entry := range tx.journal.Entries {
+		if err := tx.apply(entry); err != nil {
+			if rollbackErr := tx.rollback(); rollbackErr != nil {
+				return fmt.Errorf("%v (rollback failed: %v)", err, rollbackErr)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Applies the given change, backing up the original file.
+func (tx *transaction) apply(entry *journalEntry) error {
+	path := tx.path(entry)
+
+	if entry.Existed {
+		backup := filepath.Join(tx.dir, "backup", filepath.FromSlash(entry.Path))
+		if err := os.MkdirAll(filepath.Dir(backup), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.Rename(path, backup); err != nil {
+			return err
+		}
+	}
+
+	if !entry.Delete {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		staged := filepath.Join(tx.dir, "staging", filepath.FromSlash(entry.Path))
+		if err := os.Rename(staged, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Restores every file changed by the transaction, and discards it.
+func (tx *transaction) rollback() error {
+	var errs []error
+	for j := len(tx.journal.Entries) - 1; j >= 0; j-- {
+		entry := tx.journal.Entries[j]
+		path := tx.path(entry)
+
+		if entry.Existed {
+			// If the file hasn't been backed up, it was never touched
+			backup := filepath.Join(tx.dir, "backup", filepath.FromSlash(entry.Path))
+			if _, err := os.Lstat(backup); err != nil {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+				continue
+			}
+			if err := os.Rename(backup, path); err != nil {
+				errs = append(errs, err)
+			}
+		} else if !entry.Delete {
+			// The file is new, so remove it
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if err := tx.restoreSnapshot(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Keep the journal around, so the next install can try again
+	if len(errs) > 0 {
+		return fmt.Errorf("ftb: failed to restore %d file(s), first error: %v", len(errs), errs[0])
+	}
+	return tx.discard()
+}
+
+// Completes the transaction, once all changes have been committed.
+func (tx *transaction) finish() error {
+	// Once the journal is gone, there's nothing to roll back
+	if err := os.Remove(filepath.Join(tx.dir, journalFile)); err != nil {
+		return err
+	}
+	return tx.discard()
+}
+
+// Abandons the transaction before it has been committed, restoring any
+// snapshot and discarding it.
+// Should the snapshot fail to be restored, the journal is kept around - so
+// the next install can try again.
+func (tx *transaction) abort() error {
+	if err := tx.restoreSnapshot(); err != nil {
+		return err
+	}
+	return tx.discard()
+}
+
+// Removes the transaction's files, without touching the install.
+func (tx *transaction) discard() error {
+	return os.RemoveAll(tx.dir)
+}
+
+func (tx *transaction) path(entry *journalEntry) string {
+	return filepath.Join(tx.dest, filepath.FromSlash(entry.Path))
+}
+
+func (tx *transaction) writeJournal() error {
+	// Write the journal atomically, so it's never seen half written
+	tmp := filepath.Join(tx.dir, journalFile+".tmp")
+	if err := writeJson(tmp, tx.journal); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(tx.dir, journalFile))
+}
@@ -0,0 +1,228 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbtx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	installer := NewInstaller(1)
+	writeTestFile(t, dest, "mods/updated.jar", "old")
+	writeTestFile(t, dest, "mods/removed.jar", "old")
+
+	tx, err := installer.beginTransaction(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageTestFile(t, tx, filepath.Join(dest, "mods", "updated.jar"), "new")
+	stageTestFile(t, tx, filepath.Join(dest, "mods", "added.jar"), "new")
+	if err := tx.remove(filepath.Join(dest, "mods", "removed.jar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTestFile(t, dest, "mods/updated.jar", "new")
+	assertTestFile(t, dest, "mods/added.jar", "new")
+	assertTestFile(t, dest, "mods/removed.jar", "")
+
+	// Simulate the install being killed, and recovered by the next
+	if err := installer.recoverTransaction(dest); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTestFile(t, dest, "mods/updated.jar", "old")
+	assertTestFile(t, dest, "mods/added.jar", "")
+	assertTestFile(t, dest, "mods/removed.jar", "old")
+	if _, err := os.Stat(tx.dir); !os.IsNotExist(err) {
+		t.Errorf("transaction directory should have been removed")
+	}
+}
+
+func TestTransactionSnapshot(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbtx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	installer := NewInstaller(1)
+	writeTestFile(t, dest, "run.sh", "old")
+	writeTestFile(t, dest, "server.jar", "old")
+
+	tx, err := installer.beginTransaction(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.snapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a modloader installer changing the install, before the
+	// install is killed and recovered by the next
+	writeTestFile(t, dest, "run.sh", "new")
+	writeTestFile(t, dest, "loader.jar", "new")
+	writeTestFile(t, dest, "libraries/loader.jar", "new")
+	if err := installer.recoverTransaction(dest); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTestFile(t, dest, "run.sh", "old")
+	assertTestFile(t, dest, "server.jar", "old")
+	assertTestFile(t, dest, "loader.jar", "")
+	assertTestFile(t, dest, "libraries/loader.jar", "new")
+	if _, err := os.Stat(tx.dir); !os.IsNotExist(err) {
+		t.Errorf("transaction directory should have been removed")
+	}
+}
+
+func TestInstallPlanModLoaderFailure(t *testing.T) {
+	// Serves Fabric Loader, and a version of Quilt Loader that fails to
+	// download part way through
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/versions/loader/1.19.2/0.14.9/server/json":
+			fmt.Fprintf(w, `{"id": "fabric-loader-0.14.9-1.19.2", "mainClass": "net.fabricmc.loader.impl.launch.knot.KnotServer", "libraries": [
+				{"name": "net.fabricmc:fabric-loader:0.14.9", "url": "%s/maven/"}
+			]}`, server.URL)
+		case "/v3/versions/loader/1.19.2/0.17.0/server/json":
+			fmt.Fprintf(w, `{"id": "quilt-loader-0.17.0-1.19.2", "mainClass": "org.quiltmc.loader.impl.launch.knot.KnotServer", "libraries": [
+				{"name": "org.quiltmc:quilt-loader:0.17.0", "url": "%s/maven/"}
+			]}`, server.URL)
+		case "/maven/net/fabricmc/fabric-loader/0.14.9/fabric-loader-0.14.9.jar":
+			fmt.Fprint(w, "jar")
+		case "/new.jar":
+			fmt.Fprint(w, "world")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dest, err := ioutil.TempDir("", "ftbtx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	installer := NewInstaller(1)
+	installer.FabricInstaller.MetaRoot, _ = url.Parse(server.URL + "/")
+	installer.QuiltInstaller.MetaRoot, _ = url.Parse(server.URL + "/")
+
+	// An existing install, of an older version of the pack
+	writeTestFile(t, dest, "server.jar", "vanilla")
+	writeTestFile(t, dest, "fabric-server-launch.jar", "old")
+	writeTestFile(t, dest, "mods/old.jar", "hello")
+	if err := os.MkdirAll(filepath.Join(dest, installer.DataDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	settingsPath := filepath.Join(dest, installer.DataDir, settingsFile)
+	if err := writeJson(settingsPath, &InstallSettings{
+		ID:      "test",
+		Pack:    1,
+		Version: 1,
+		Target:  minecraft.Server,
+		Files: map[string]string{
+			"./mods/old.jar": helloSha1,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	originalSettings, err := ioutil.ReadFile(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pack := &modpacksch.Pack{ID: 1, Name: "Test"}
+	version := &modpacksch.PackVersion{
+		ID:   2,
+		Name: "1.1.0",
+		Targets: []*modpacksch.Target{
+			{Type: "game", Name: "minecraft", Version: "1.19.2"},
+			{Type: "modloader", Name: "fabric", Version: "0.14.9"},
+			{Type: "modloader", Name: "quilt", Version: "0.17.0"},
+		},
+		Files: []*modpacksch.File{
+			{Path: "./mods/", Name: "new.jar", URL: server.URL + "/new.jar", Sha1: worldSha1, Size: 5},
+		},
+	}
+	plan, err := installer.PlanPackVersion(minecraft.Server, dest, pack, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := installer.installPlan(context.Background(), plan); err == nil {
+		t.Fatal("install should have failed")
+	}
+
+	// Fabric Loader's changes to the install should have been undone
+	assertTestFile(t, dest, "server.jar", "vanilla")
+	assertTestFile(t, dest, "fabric-server-launch.jar", "old")
+	assertTestFile(t, dest, "quilt-server-launch.jar", "")
+
+	// And the pack left as it was
+	assertTestFile(t, dest, "mods/old.jar", "hello")
+	assertTestFile(t, dest, "mods/new.jar", "")
+	if settings, err := ioutil.ReadFile(settingsPath); err != nil || string(settings) != string(originalSettings) {
+		t.Errorf("install settings should be unchanged, got %s (%v)", settings, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, installer.DataDir, transactionDir)); !os.IsNotExist(err) {
+		t.Errorf("transaction directory should have been removed")
+	}
+}
+
+func stageTestFile(t *testing.T, tx *transaction, path string, contents string) {
+	stagingPath, err := tx.stagingPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(stagingPath), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(stagingPath, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.write(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Asserts the file has the given contents, an empty string meaning the
+// file shouldn't exist.
+func assertTestFile(t *testing.T, dest string, path string, contents string) {
+	data, err := ioutil.ReadFile(filepath.Join(dest, filepath.FromSlash(path)))
+	if contents == "" {
+		if !os.IsNotExist(err) {
+			t.Errorf("%s should not exist", path)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("failed to read %s: %v", path, err)
+		return
+	}
+	if string(data) != contents {
+		t.Errorf("%s is '%s', should be '%s'", path, data, contents)
+	}
+}
@@ -0,0 +1,263 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/minecraft/launcher"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+// UninstallOptions configures how a pack is uninstalled.
+type UninstallOptions struct {
+	// Whether to remove the modloader version the pack's profile used
+	// from the launcher directory, should no other profile use it.
+	RemoveModLoader bool
+}
+
+// KeptFile describes a file that was left in place by an uninstall.
+type KeptFile struct {
+	Path   string
+	Reason string
+}
+
+// UninstallResult describes what an uninstall did.
+type UninstallResult struct {
+	// The pack files, and generated launch files, that were removed
+	Removed []string
+
+	// The pack files that were left in place, and why
+	Kept []*KeptFile
+
+	// Whether the pack's launcher profile was removed
+	ProfileRemoved bool
+
+	// The modloader version that was removed from the launcher, if any
+	RemovedVersion string
+
+	// Why the modloader version wasn't removed, if it wasn't - as a
+	// clause, such as "it is still used by another profile"
+	VersionKeptReason string
+}
+
+// Uninstalls the pack installed at the given destination.
+// Only pack files (and generated launch files) that are unmodified are
+// removed, files modified by the player and anything protected by a rule
+// are left in place. Should install.json record a file outside of the
+// install, nothing is removed and ErrPathOutsideInstall is returned.
+func (i *Installer) Uninstall(dest string, opts *UninstallOptions) (*UninstallResult, error) {
+	if opts == nil {
+		opts = &UninstallOptions{}
+	}
+
+	destination, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Roll back any changes from an interrupted install, so install.json
+	// is accurate
+	if err := i.recoverTransaction(destination); err != nil {
+		return nil, err
+	}
+
+	settings, err := i.GetInstallSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := i.loadProtectRules(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check every file is within the install, before anything is removed
+	var files []*uninstallFile
+	for path, hash := range settings.knownFiles() {
+		file, err := newUninstallFile(destination, path, hash, "modified since it was installed")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	for name, hash := range settings.LaunchConfig {
+		file, err := newUninstallFile(destination, name, hash, "modified since it was generated")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].path < files[b].path
+	})
+
+	// Remove the launcher profile first, so that should it fail the pack
+	// is still installed - and the uninstall can be tried again. A missing
+	// profile (or launcher) is taken to have already been removed.
+	result := &UninstallResult{}
+	if settings.Target == minecraft.Client {
+		err := launcher.RemoveProfile(settings.ID)
+		if err != nil && !os.IsNotExist(err) && !errors.Is(err, launcher.ErrProfileDoesntExist) {
+			return nil, err
+		}
+		result.ProfileRemoved = err == nil
+
+		if opts.RemoveModLoader {
+			if err := removeProfileVersion(settings, result); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Remove unmodified pack files, and launch files
+	for _, file := range files {
+		info, err := os.Stat(file.dest)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		if rule := matchProtectRules(rules, file.relPath); rule != nil {
+			result.Kept = append(result.Kept, &KeptFile{
+				Path:   file.path,
+				Reason: "protected by " + rule.String(),
+			})
+			continue
+		}
+
+		hash, err := util.HashFile(file.dest)
+		if err != nil {
+			return nil, err
+		}
+		if hash != file.hash {
+			result.Kept = append(result.Kept, &KeptFile{
+				Path:   file.path,
+				Reason: file.modifiedReason,
+			})
+			continue
+		}
+
+		if err := os.Remove(file.dest); err != nil {
+			return nil, err
+		}
+		removeEmptyDirs(destination, filepath.Dir(file.dest))
+		result.Removed = append(result.Removed, file.path)
+	}
+
+	// Remove install settings, keeping any files diverted by previous
+	// installs
+	if err := os.Remove(filepath.Join(destination, i.DataDir, settingsFile)); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(filepath.Join(destination, i.DataDir, pristineDir)); err != nil {
+		return nil, err
+	}
+	removeEmptyDirs(destination, filepath.Join(destination, i.DataDir))
+
+	return result, nil
+}
+
+// A file recorded by install.json, that is to be removed should it be
+// unmodified.
+type uninstallFile struct {
+	// The path as recorded by install.json
+	path string
+
+	// The path of the file on disk, and relative to the install
+	dest    string
+	relPath string
+
+	// The sha1 hash the file was installed with
+	hash string
+
+	// Why the file is kept, should it not match its hash
+	modifiedReason string
+}
+
+func newUninstallFile(dest string, path string, hash string, modifiedReason string) (*uninstallFile, error) {
+	fileDest, relPath, err := resolveInstallPath(dest, path)
+	if err != nil {
+		return nil, err
+	}
+	return &uninstallFile{
+		path:           path,
+		dest:           fileDest,
+		relPath:        relPath,
+		hash:           hash,
+		modifiedReason: modifiedReason,
+	}, nil
+}
+
+// Removes the modloader version used by the install's profile, should no
+// other profile (or version) still use it.
+func removeProfileVersion(settings *InstallSettings, result *UninstallResult) error {
+	if settings.ProfileVersion == "" {
+		result.VersionKeptReason = "install.json doesn't record the version used"
+		return nil
+	}
+
+	// Without a modloader, the version is vanilla Minecraft - which
+	// isn't ours to remove
+	usesModLoader := false
+	for _, target := range settings.Targets {
+		if target.Type == "modloader" {
+			usesModLoader = true
+		}
+	}
+	if !usesModLoader {
+		result.VersionKeptReason = "the pack doesn't use a modloader"
+		return nil
+	}
+
+	profiles, err := launcher.GetProfilesUsingVersion(settings.ProfileVersion)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(profiles) > 0 {
+		result.VersionKeptReason = "it is still used by another profile"
+		return nil
+	}
+
+	// Such as LiteLoader, chained onto Minecraft Forge
+	launcherDir := launcher.GetLauncherDir()
+	versions, err := launcher.GetVersionsInheritingFrom(launcherDir, settings.ProfileVersion)
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 {
+		result.VersionKeptReason = "it is inherited by " + versions[0]
+		return nil
+	}
+
+	if err := launcher.RemoveVersion(launcherDir, settings.ProfileVersion); err != nil {
+		if errors.Is(err, launcher.ErrInvalidVersionName) {
+			result.VersionKeptReason = "install.json records an invalid name for it"
+			return nil
+		}
+		return err
+	}
+	result.RemovedVersion = settings.ProfileVersion
+	return nil
+}
+
+// Removes the given directory, and its parents up to the root, for as
+// long as they are empty.
+func removeEmptyDirs(root string, dir string) {
+	for dir != root && len(dir) > len(root) {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,200 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+func TestUninstall(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbuninstall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	// The launcher has no launcher_profiles.json, as if it were removed
+	// by the player (or a previous uninstall)
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+	os.Setenv("HOME", filepath.Join(dest, "home"))
+
+	installer := NewInstaller(1)
+	install := func(target minecraft.InstallTarget) {
+		writeTestFile(t, dest, "mods/same.jar", "hello")
+		writeTestFile(t, dest, "config/modified.cfg", "modified")
+		writeTestFile(t, dest, "start.sh", "hello")
+		writeTestFile(t, dest, "user_jvm_args.txt", "modified")
+		if err := os.MkdirAll(filepath.Join(dest, installer.DataDir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeJson(filepath.Join(dest, installer.DataDir, settingsFile), &InstallSettings{
+			ID:      "test",
+			Pack:    1,
+			Version: 1,
+			Target:  target,
+			Files: map[string]string{
+				"./mods/same.jar":       helloSha1,
+				"./config/modified.cfg": helloSha1,
+			},
+			LaunchConfig: map[string]string{
+				"start.sh":          helloSha1,
+				"user_jvm_args.txt": helloSha1,
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Server
+	install(minecraft.Server)
+	result, err := installer.Uninstall(dest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"./mods/same.jar", "start.sh"}; !reflect.DeepEqual(result.Removed, expected) {
+		t.Errorf("removed %v, should have removed %v", result.Removed, expected)
+	}
+	if len(result.Kept) != 2 || result.Kept[0].Path != "./config/modified.cfg" || result.Kept[1].Path != "user_jvm_args.txt" {
+		t.Errorf("unexpected kept files: %+v", result.Kept)
+	}
+	assertTestFile(t, dest, "mods/same.jar", "")
+	assertTestFile(t, dest, "start.sh", "")
+	assertTestFile(t, dest, "config/modified.cfg", "modified")
+	assertTestFile(t, dest, "user_jvm_args.txt", "modified")
+	assertTestFile(t, dest, installer.DataDir+"/"+settingsFile, "")
+
+	// Client, where the launcher's profile has already gone
+	install(minecraft.Client)
+	result, err = installer.Uninstall(dest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ProfileRemoved {
+		t.Error("the profile shouldn't have been removed, as it doesn't exist")
+	}
+	assertTestFile(t, dest, installer.DataDir+"/"+settingsFile, "")
+}
+
+func TestUninstallOutsideInstall(t *testing.T) {
+	root, err := ioutil.TempDir("", "ftbuninstall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	dest := filepath.Join(root, "instance")
+	writeTestFile(t, root, "outside.txt", "hello")
+	writeTestFile(t, dest, "mods/same.jar", "hello")
+
+	installer := NewInstaller(1)
+	if err := os.MkdirAll(filepath.Join(dest, installer.DataDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJson(filepath.Join(dest, installer.DataDir, settingsFile), &InstallSettings{
+		ID:      "test",
+		Pack:    1,
+		Version: 1,
+		Target:  minecraft.Server,
+		Files: map[string]string{
+			"./mods/same.jar": helloSha1,
+			"../outside.txt":  helloSha1,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := installer.Uninstall(dest, nil); !errors.Is(err, ErrPathOutsideInstall) {
+		t.Errorf("uninstall should have been rejected, got %v", err)
+	}
+
+	// Nothing should have been removed
+	assertTestFile(t, root, "outside.txt", "hello")
+	assertTestFile(t, dest, "mods/same.jar", "hello")
+	if _, err := os.Stat(filepath.Join(dest, installer.DataDir, settingsFile)); err != nil {
+		t.Errorf("install settings should have been kept: %v", err)
+	}
+}
+
+func TestUninstallRemoveModLoader(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbuninstall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	home := os.Getenv("HOME")
+	defer os.Setenv("HOME", home)
+	os.Setenv("HOME", filepath.Join(dest, "home"))
+	launcherDir := filepath.Join(dest, "home", ".minecraft")
+	writeVersion := func(name string, inheritsFrom string) {
+		writeTestFile(t, launcherDir, "versions/"+name+"/"+name+".json", `{"id": "`+name+`", "inheritsFrom": "`+inheritsFrom+`"}`)
+	}
+	writeVersion("1.19.2", "")
+	writeVersion("1.19.2-forge-43.1.1", "1.19.2")
+	writeVersion("liteloader", "1.19.2-forge-43.1.1")
+
+	installer := NewInstaller(1)
+	uninstall := func(profileVersion string, targets []*InstalledTarget) *UninstallResult {
+		if err := os.MkdirAll(filepath.Join(dest, "instance", installer.DataDir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeJson(filepath.Join(dest, "instance", installer.DataDir, settingsFile), &InstallSettings{
+			ID:             "test",
+			Pack:           1,
+			Version:        1,
+			Target:         minecraft.Client,
+			ProfileVersion: profileVersion,
+			Targets:        targets,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		result, err := installer.Uninstall(filepath.Join(dest, "instance"), &UninstallOptions{
+			RemoveModLoader: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	forge := []*InstalledTarget{
+		{Name: "minecraft", Type: "game", Version: "1.19.2"},
+		{Name: "forge", Type: "modloader", Version: "43.1.1"},
+	}
+
+	// Vanilla Minecraft is never removed
+	if result := uninstall("1.19.2", forge[:1]); result.RemovedVersion != "" || result.VersionKeptReason == "" {
+		t.Errorf("vanilla version shouldn't have been removed: %+v", result)
+	}
+
+	// Nor is anything outside of the versions directory
+	for _, name := range []string{"..", "../..", "../versions/1.19.2"} {
+		if result := uninstall(name, forge); result.RemovedVersion != "" || result.VersionKeptReason == "" {
+			t.Errorf("%s shouldn't have been removed: %+v", name, result)
+		}
+	}
+	assertTestFile(t, launcherDir, "versions/1.19.2/1.19.2.json", `{"id": "1.19.2", "inheritsFrom": ""}`)
+
+	// Nor a version another inherits from
+	if result := uninstall("1.19.2-forge-43.1.1", forge); result.RemovedVersion != "" || result.VersionKeptReason != "it is inherited by liteloader" {
+		t.Errorf("inherited version shouldn't have been removed: %+v", result)
+	}
+
+	if err := os.RemoveAll(filepath.Join(launcherDir, "versions", "liteloader")); err != nil {
+		t.Fatal(err)
+	}
+	if result := uninstall("1.19.2-forge-43.1.1", forge); result.RemovedVersion != "1.19.2-forge-43.1.1" {
+		t.Errorf("version should have been removed: %+v", result)
+	}
+	assertTestFile(t, launcherDir, "versions/1.19.2-forge-43.1.1/1.19.2-forge-43.1.1.json", "")
+	assertTestFile(t, launcherDir, "versions/1.19.2/1.19.2.json", `{"id": "1.19.2", "inheritsFrom": ""}`)
+}
@@ -0,0 +1,323 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+var (
+	VersionNotInstalled = errors.New("ftb: the given pack version is not the one installed")
+)
+
+// FileStatus describes the state of an installed file, compared to what
+// was recorded when it was installed.
+type FileStatus int
+
+const (
+	// The file is as it was installed.
+	FileOK FileStatus = iota
+
+	// The file no longer exists.
+	FileMissing
+
+	// The file is damaged, for example a truncated jar.
+	FileCorrupted
+
+	// The file has been changed, presumably by the player.
+	FileModified
+
+	// The file was recorded with a path outside of the install, so wasn't
+	// checked.
+	FileOutsideInstall
+)
+
+func (s FileStatus) String() string {
+	switch s {
+	case FileOK:
+		return "ok"
+	case FileMissing:
+		return "missing"
+	case FileCorrupted:
+		return "corrupted"
+	case FileModified:
+		return "modified"
+	case FileOutsideInstall:
+		return "outside of the install"
+	default:
+		return "unknown"
+	}
+}
+
+// VerifiedFile describes the state of a single installed file.
+type VerifiedFile struct {
+	// The path of the file within the pack, for example
+	// ./mods/example.jar
+	Path string
+
+	// Where the file is on disk, empty if the path is outside of the
+	// install
+	Dest string
+
+	Status FileStatus
+
+	// The sha1 hash of the file on disk, if it exists
+	Hash string
+
+	// The sha1 hash recorded for the file when it was installed
+	ExpectedHash string
+}
+
+// VerifyReport describes the state of every file of an install.
+type VerifyReport struct {
+	Settings *InstallSettings
+	Files    []*VerifiedFile
+}
+
+// FilesWith gets the files that have the given status.
+func (r *VerifyReport) FilesWith(status FileStatus) []*VerifiedFile {
+	var files []*VerifiedFile
+	for _, file := range r.Files {
+		if file.Status == status {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// RepairResult describes what a repair did.
+type RepairResult struct {
+	// The state of the install, before it was repaired
+	Report *VerifyReport
+
+	// The files that were re-downloaded
+	Repaired []string
+
+	// The files that couldn't be repaired, as they are no longer a part
+	// of the pack
+	Unrepairable []string
+
+	// The files that weren't repaired, as they are protected
+	Protected []string
+}
+
+// Gets the install settings of the pack installed at the given
+// destination.
+func (i *Installer) GetInstallSettings(dest string) (*InstallSettings, error) {
+	var settings *InstallSettings
+	if readJson(filepath.Join(dest, i.DataDir, settingsFile), &settings) != nil {
+		return nil, NoPackInstalled
+	}
+	return settings, nil
+}
+
+// Verify re-hashes every file recorded for the install at the given
+// destination, reporting which are missing, corrupted or modified.
+// A file that doesn't match its recorded hash is considered corrupted if
+// it is empty, or is an archive (such as a mod jar) that can't be read -
+// otherwise it is considered to have been modified.
+// Files recorded with a path outside of the install are reported, but
+// never read.
+func (i *Installer) Verify(dest string) (*VerifyReport, error) {
+	destination, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := i.GetInstallSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for path := range settings.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	report := &VerifyReport{
+		Settings: settings,
+	}
+	for _, path := range paths {
+		file := &VerifiedFile{
+			Path:         path,
+			ExpectedHash: settings.Files[path],
+		}
+		report.Files = append(report.Files, file)
+
+		file.Dest, _, err = resolveInstallPath(destination, path)
+		if err != nil {
+			if !errors.Is(err, ErrPathOutsideInstall) {
+				return nil, err
+			}
+			file.Dest = ""
+			file.Status = FileOutsideInstall
+			continue
+		}
+
+		info, err := os.Stat(file.Dest)
+		if err != nil {
+			file.Status = FileMissing
+			continue
+		}
+
+		file.Hash, err = util.HashFile(file.Dest)
+		if err != nil {
+			return nil, err
+		}
+		if file.Hash == file.ExpectedHash {
+			file.Status = FileOK
+		} else if info.Size() == 0 || (isArchive(path) && !isReadableZip(file.Dest)) {
+			file.Status = FileCorrupted
+		} else {
+			file.Status = FileModified
+		}
+	}
+
+	return report, nil
+}
+
+// Repair re-downloads any missing or corrupted files of the install at the
+// given destination, from the given pack version - which must be the
+// version installed. Files matching the protect rules are left as they
+// are.
+func (i *Installer) Repair(ctx context.Context, dest string, version *modpacksch.PackVersion) (*RepairResult, error) {
+	destination, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Roll back any changes from an interrupted install, so install.json
+	// is accurate
+	if err := i.recoverTransaction(destination); err != nil {
+		return nil, err
+	}
+
+	report, err := i.Verify(destination)
+	if err != nil {
+		return nil, err
+	}
+	if report.Settings.Version != version.ID {
+		return nil, VersionNotInstalled
+	}
+
+	rules, err := i.loadProtectRules(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	packFiles := map[string]*modpacksch.File{}
+	for _, file := range version.Files {
+		packFiles[file.Path+file.Name] = file
+	}
+
+	result := &RepairResult{
+		Report: report,
+	}
+	var files []*PlannedFile
+	for _, file := range report.Files {
+		if file.Status != FileMissing && file.Status != FileCorrupted {
+			continue
+		}
+
+		relPath, err := filepath.Rel(destination, file.Dest)
+		if err != nil {
+			return nil, err
+		}
+		if matchProtectRules(rules, relPath) != nil {
+			result.Protected = append(result.Protected, file.Path)
+			continue
+		}
+
+		packFile := packFiles[file.Path]
+		if packFile == nil {
+			result.Unrepairable = append(result.Unrepairable, file.Path)
+			continue
+		}
+		files = append(files, &PlannedFile{
+			Path:         file.Path,
+			Dest:         file.Dest,
+			Action:       DownloadFile,
+			File:         packFile,
+			Hash:         file.Hash,
+			OriginalHash: file.ExpectedHash,
+		})
+	}
+	if len(files) == 0 {
+		return result, nil
+	}
+
+	// Stage the downloads, so a failed repair doesn't leave things worse
+	tx, err := i.beginTransaction(destination)
+	if err != nil {
+		return nil, err
+	}
+	install := &Install{
+		Version:       version.ID,
+		OriginalFiles: report.Settings.Files,
+		NewFiles:      map[string]string{},
+		dest:          destination,
+	}
+	filesErr := i.installFiles(ctx, install, files, tx)
+	if filesErr != nil {
+		if e, ok := filesErr.(*FilesError); !ok || e.Aborted {
+			tx.discard()
+			return nil, filesErr
+		}
+	}
+	if err := tx.commit(); err != nil {
+		return nil, err
+	}
+	if err := tx.finish(); err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if install.NewFiles[file.Path] == file.File.Sha1 {
+			result.Repaired = append(result.Repaired, file.Path)
+		}
+	}
+	return result, filesErr
+}
+
+// Whether the file at the given path is expected to be a zip archive.
+func isArchive(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".jar" || ext == ".zip"
+}
+
+// Whether the file at the given path can be read as a zip archive.
+func isReadableZip(path string) bool {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		// Reading the whole file checks its CRC
+		r, err := file.Open()
+		if err != nil {
+			return false
+		}
+		_, err = io.Copy(ioutil.Discard, r)
+		r.Close()
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
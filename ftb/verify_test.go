@@ -0,0 +1,111 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+)
+
+func TestVerify(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbverify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	installer := NewInstaller(1)
+	writeTestFile(t, dest, "config/ok.cfg", "hello")
+	writeTestFile(t, dest, "config/modified.cfg", "modified")
+	writeTestFile(t, dest, "mods/truncated.jar", "PK")
+	writeTestFile(t, dest, "mods/empty.jar", "")
+	if err := os.MkdirAll(filepath.Join(dest, installer.DataDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJson(filepath.Join(dest, installer.DataDir, settingsFile), &InstallSettings{
+		ID:      "test",
+		Pack:    1,
+		Version: 1,
+		Files: map[string]string{
+			"./config/ok.cfg":       helloSha1,
+			"./config/modified.cfg": helloSha1,
+			"./mods/truncated.jar":  helloSha1,
+			"./mods/empty.jar":      helloSha1,
+			"./mods/missing.jar":    helloSha1,
+			"../outside.cfg":        helloSha1,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := installer.Verify(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]FileStatus{
+		"./config/ok.cfg":       FileOK,
+		"./config/modified.cfg": FileModified,
+		"./mods/truncated.jar":  FileCorrupted,
+		"./mods/empty.jar":      FileCorrupted,
+		"./mods/missing.jar":    FileMissing,
+		"../outside.cfg":        FileOutsideInstall,
+	}
+	for _, file := range report.Files {
+		if file.Status != expected[file.Path] {
+			t.Errorf("%s is %s, should be %s", file.Path, file.Status, expected[file.Path])
+		}
+	}
+}
+
+func TestRepairProtected(t *testing.T) {
+	dest, err := ioutil.TempDir("", "ftbrepair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dest)
+
+	installer := NewInstaller(1)
+	installer.Protect = []string{"config/"}
+	if err := os.MkdirAll(filepath.Join(dest, installer.DataDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJson(filepath.Join(dest, installer.DataDir, settingsFile), &InstallSettings{
+		ID:      "test",
+		Pack:    1,
+		Version: 1,
+		Files: map[string]string{
+			"./config/deleted.cfg": helloSha1,
+			"../outside.cfg":       helloSha1,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := installer.Repair(context.Background(), dest, &modpacksch.PackVersion{
+		ID: 1,
+		Files: []*modpacksch.File{
+			{Path: "./config/", Name: "deleted.cfg", Sha1: helloSha1},
+			{Path: "../", Name: "outside.cfg", Sha1: helloSha1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Repaired) != 0 {
+		t.Errorf("repaired %v, should have repaired nothing", result.Repaired)
+	}
+	if len(result.Protected) != 1 || result.Protected[0] != "./config/deleted.cfg" {
+		t.Errorf("protected %v, should be ./config/deleted.cfg", result.Protected)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "config", "deleted.cfg")); !os.IsNotExist(err) {
+		t.Error("protected file was re-downloaded")
+	}
+}
@@ -0,0 +1,88 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+)
+
+var (
+	VersionNotFound = errors.New("ftb: no version of the pack matches")
+)
+
+const (
+	// The newest version of the pack, of any type.
+	LatestVersion = "latest"
+
+	// The newest release of the pack.
+	LatestRelease = "latest-release"
+
+	// The newest beta (or release, should it be newer) of the pack.
+	LatestBeta = "latest-beta"
+)
+
+// ResolveVersion finds the version of the given pack that the query refers
+// to. The query can be a version ID, a version name (such as 1.4.2) or one
+// of LatestVersion, LatestRelease and LatestBeta.
+// Should several versions share a name, the newest is used.
+func ResolveVersion(pack *modpacksch.Pack, query string) (*modpacksch.VersionInfo, error) {
+	switch strings.ToLower(query) {
+	case LatestVersion:
+		return latestVersion(pack, "release", "beta", "alpha")
+	case LatestRelease:
+		return latestVersion(pack, "release")
+	case LatestBeta:
+		return latestVersion(pack, "release", "beta")
+	}
+
+	if id, err := strconv.Atoi(query); err == nil {
+		for _, version := range pack.Versions {
+			if version.ID == id {
+				return version, nil
+			}
+		}
+	}
+
+	var found *modpacksch.VersionInfo
+	for _, version := range pack.Versions {
+		if version.Name == query && (found == nil || version.ID > found.ID) {
+			found = version
+		}
+	}
+	if found == nil {
+		return nil, VersionNotFound
+	}
+	return found, nil
+}
+
+// Gets the newest version of the pack, that has one of the given types.
+func latestVersion(pack *modpacksch.Pack, types ...string) (*modpacksch.VersionInfo, error) {
+	var latest *modpacksch.VersionInfo
+	for _, version := range pack.Versions {
+		if !hasVersionType(version, types) {
+			continue
+		}
+		if latest == nil || version.ID > latest.ID {
+			latest = version
+		}
+	}
+	if latest == nil {
+		return nil, VersionNotFound
+	}
+	return latest, nil
+}
+
+func hasVersionType(version *modpacksch.VersionInfo, types []string) bool {
+	for _, t := range types {
+		if strings.EqualFold(version.Type, t) {
+			return true
+		}
+	}
+	return false
+}
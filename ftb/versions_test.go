@@ -0,0 +1,45 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ftb
+
+import (
+	"testing"
+
+	"git.sr.ht/~jmansfield/go-modpacksch/modpacksch"
+)
+
+func TestResolveVersion(t *testing.T) {
+	pack := &modpacksch.Pack{
+		ID: 1,
+		Versions: []*modpacksch.VersionInfo{
+			{ID: 10, Name: "1.0.0", Type: "Release"},
+			{ID: 11, Name: "1.1.0", Type: "Beta"},
+			{ID: 12, Name: "1.1.0", Type: "Release"},
+			{ID: 13, Name: "1.2.0", Type: "Beta"},
+			{ID: 14, Name: "1.3.0", Type: "Alpha"},
+		},
+	}
+
+	expected := map[string]int{
+		"latest":         14,
+		"latest-release": 12,
+		"latest-beta":    13,
+		"1.0.0":          10,
+		"1.1.0":          12,
+		"11":             11,
+	}
+	for query, id := range expected {
+		version, err := ResolveVersion(pack, query)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+		} else if version.ID != id {
+			t.Errorf("%s should resolve to %d, but resolved to %d", query, id, version.ID)
+		}
+	}
+
+	if _, err := ResolveVersion(pack, "2.0.0"); err != VersionNotFound {
+		t.Errorf("2.0.0 should not be found, got %v", err)
+	}
+}
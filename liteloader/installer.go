@@ -0,0 +1,244 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package liteloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/minecraft/launcher"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	defaultVersionsURL = "https://dl.liteloader.com/versions/versions.json"
+
+	// The tweaker LiteLoader is launched with, should its build not give
+	// one
+	defaultTweakClass = "com.mumfrey.liteloader.launch.LiteLoaderTweaker"
+
+	// LaunchWrapper, which launches LiteLoader's tweaker
+	launchWrapperMainClass = "net.minecraft.launchwrapper.Launch"
+)
+
+var (
+	ErrVersionNotFound = errors.New("liteloader: version not found")
+
+	// LiteLoader can only be installed on a server as a mod of Minecraft
+	// Forge.
+	ErrServerUnsupported = errors.New("liteloader: servers are only supported alongside Minecraft Forge")
+)
+
+type Installer struct {
+	// The URL to LiteLoader's list of versions, or a mirror.
+	VersionsURL *url.URL
+}
+
+// NewInstaller returns a new Installer to use for installing LiteLoader.
+func NewInstaller() *Installer {
+	versionsURL, _ := url.Parse(defaultVersionsURL)
+
+	return &Installer{
+		VersionsURL: versionsURL,
+	}
+}
+
+// VersionName gets the name of the launcher version for LiteLoader, chained
+// onto the given launcher version - or vanilla Minecraft, should no version
+// be given. For example 1.12.2-LiteLoader1.12.2.
+func VersionName(mcVersion *minecraft.Version, parent string) string {
+	if parent == "" {
+		parent = mcVersion.String()
+	}
+	return parent + "-LiteLoader" + mcVersion.String()
+}
+
+// Installs LiteLoader to the given destination, for the given target.
+// If the target is Server, the destination will be the root directory of
+// the server; if the target is Client, the destination will be the
+// launcher's root directory.
+//
+// LiteLoader is chained onto the given launcher version, such as that of
+// Minecraft Forge, or vanilla Minecraft should no version be given. For
+// servers, a parent means LiteLoader is installed as a mod of Minecraft
+// Forge - as it can't be installed on a server by itself.
+func (i *Installer) InstallLiteLoader(target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, version string, parent string) error {
+	return i.InstallLiteLoaderContext(context.Background(), target, dest, mcVersion, version, parent)
+}
+
+// See InstallLiteLoader
+// Should the context be cancelled, any in-progress download will be
+// aborted.
+func (i *Installer) InstallLiteLoaderContext(ctx context.Context, target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, version string, parent string) error {
+	if target == minecraft.Server && parent == "" {
+		return ErrServerUnsupported
+	}
+	versionName := VersionName(mcVersion, parent)
+
+	// Check whether we need to install LiteLoader
+	modPath := filepath.Join(dest, "mods", "liteloader-"+version+".jar")
+	_, serverCheck := os.Stat(modPath)
+	_, clientCheck := os.Stat(filepath.Join(dest, "versions", versionName, versionName+".json"))
+	if (serverCheck == nil && target == minecraft.Server) ||
+		(clientCheck == nil && target == minecraft.Client) {
+		fmt.Println("LiteLoader install found, skipping...")
+		return nil
+	}
+	fmt.Printf("Installing LiteLoader %s for Minecraft %s...\n", version, mcVersion)
+
+	build, err := i.GetBuild(ctx, mcVersion, version)
+	if err != nil {
+		return err
+	}
+
+	// Minecraft Forge loads LiteLoader as a mod, by way of its tweaker
+	if target == minecraft.Server {
+		return downloadJar(ctx, build.URL, modPath)
+	}
+
+	// Install LiteLoader as a library
+	liteLoader := &Library{
+		Name: "com.mumfrey:liteloader:" + build.Version,
+	}
+	liteLoaderPath, err := liteLoader.Path()
+	if err != nil {
+		return err
+	}
+	if err := downloadJar(ctx, build.URL, filepath.Join(dest, "libraries", filepath.FromSlash(liteLoaderPath))); err != nil {
+		return err
+	}
+
+	// The parent's arguments are replaced, so LiteLoader's tweaker is
+	// chained onto them
+	if parent == "" {
+		parent = mcVersion.String()
+		if err := launcher.InstallClientVersionContext(ctx, dest, parent); err != nil {
+			return err
+		}
+	}
+	arguments, parentLibraries, err := readParent(dest, parent)
+	if err != nil {
+		return err
+	}
+
+	// Libraries the parent already has (such as LaunchWrapper, for
+	// Minecraft Forge) needn't be added again
+	libraries := []*launcher.VersionLibrary{{Name: liteLoader.Name}}
+	for _, library := range build.Libraries {
+		if parentLibraries[artifactName(library.Name)] {
+			continue
+		}
+		if err := downloadLibrary(ctx, dest, library); err != nil {
+			return err
+		}
+		libraries = append(libraries, &launcher.VersionLibrary{
+			Name: library.Name,
+			URL:  library.URL,
+		})
+	}
+
+	versionDir := filepath.Join(dest, "versions", versionName)
+	if err := os.MkdirAll(versionDir, os.ModePerm); err != nil {
+		return err
+	}
+	versionFile, err := os.Create(filepath.Join(versionDir, versionName+".json"))
+	if err != nil {
+		return err
+	}
+	defer versionFile.Close()
+	encoder := json.NewEncoder(versionFile)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(&launcher.Version{
+		ID:                 versionName,
+		Type:               "release",
+		InheritsFrom:       parent,
+		Jar:                mcVersion.String(),
+		MainClass:          launchWrapperMainClass,
+		Libraries:          libraries,
+		MinecraftArguments: strings.TrimSpace(arguments + " --tweakClass " + build.TweakClass),
+	})
+}
+
+// Reads the arguments of the given launcher version, and the libraries
+// (by group and artifact) of it and the versions it inherits from.
+func readParent(launcherDir string, id string) (string, map[string]bool, error) {
+	var arguments string
+	libraries := map[string]bool{}
+	for id != "" {
+		var version struct {
+			InheritsFrom       string `json:"inheritsFrom"`
+			MinecraftArguments string `json:"minecraftArguments"`
+			Libraries          []struct {
+				Name string `json:"name"`
+			} `json:"libraries"`
+		}
+		f, err := os.Open(filepath.Join(launcherDir, "versions", id, id+".json"))
+		if err != nil {
+			return "", nil, err
+		}
+		err = json.NewDecoder(f).Decode(&version)
+		f.Close()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if arguments == "" {
+			arguments = version.MinecraftArguments
+		}
+		for _, library := range version.Libraries {
+			libraries[artifactName(library.Name)] = true
+		}
+		id = version.InheritsFrom
+	}
+	return arguments, libraries, nil
+}
+
+// Gets the group and artifact of the given Maven artifact.
+func artifactName(name string) string {
+	parts := strings.SplitN(name, ":", 3)
+	if len(parts) < 2 {
+		return name
+	}
+	return parts[0] + ":" + parts[1]
+}
+
+// Downloads the given library, to the libraries directory within the
+// given destination - should it not already exist.
+func downloadLibrary(ctx context.Context, dest string, library *Library) error {
+	path, err := library.Path()
+	if err != nil {
+		return err
+	}
+	u, err := library.DownloadURL()
+	if err != nil {
+		return err
+	}
+	return downloadJar(ctx, u, filepath.Join(dest, "libraries", filepath.FromSlash(path)))
+}
+
+// Downloads the jar at the given URL to the given path, should it not
+// already exist.
+func downloadJar(ctx context.Context, u string, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/java-archive")
+
+	_, err = util.DownloadFile(req, path, "", 0)
+	return err
+}
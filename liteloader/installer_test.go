@@ -0,0 +1,131 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package liteloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/minecraft/launcher"
+)
+
+// Serves a mock of LiteLoader's list of versions, and repositories
+func newMockServer() *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/versions.json":
+			fmt.Fprintf(w, `{"versions": {"1.12.2": {
+				"repo": {"stream": "RELEASE", "url": "%s/versions/"},
+				"snapshots": {
+					"repo": {"stream": "SNAPSHOT", "url": "%s/snapshots/"},
+					"libraries": [
+						{"name": "net.minecraft:launchwrapper:1.12"},
+						{"name": "org.ow2.asm:asm-all:5.2", "url": "%s/maven/"}
+					],
+					"com.mumfrey:liteloader": {
+						"latest": {"version": "1.12.2-SNAPSHOT", "file": "liteloader-1.12.2-SNAPSHOT.jar", "stream": "SNAPSHOT"},
+						"1.12.2-SNAPSHOT": {"version": "1.12.2-SNAPSHOT", "file": "liteloader-1.12.2-SNAPSHOT.jar", "stream": "SNAPSHOT", "tweakClass": "com.mumfrey.liteloader.launch.LiteLoaderTweaker"}
+					}
+				}
+			}}}`, server.URL, server.URL, server.URL)
+		case "/snapshots/com/mumfrey/liteloader/1.12.2-SNAPSHOT/liteloader-1.12.2-SNAPSHOT.jar",
+			"/maven/org/ow2/asm/asm-all/5.2/asm-all-5.2.jar":
+			fmt.Fprint(w, "jar")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestInstallLiteLoader(t *testing.T) {
+	server := newMockServer()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "liteloaderinstall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	installer := NewInstaller()
+	installer.VersionsURL, _ = url.Parse(server.URL + "/versions.json")
+	mcVersion, _ := minecraft.ParseVersion("1.12.2")
+	forgeVersion := "1.12.2-forge-14.23.5.2860"
+
+	// Client, chained onto Minecraft Forge
+	{
+		launcherDir := filepath.Join(dir, "launcher")
+		forgeDir := filepath.Join(launcherDir, "versions", forgeVersion)
+		if err := os.MkdirAll(forgeDir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(forgeDir, forgeVersion+".json"), []byte(`{
+			"id": "1.12.2-forge-14.23.5.2860",
+			"minecraftArguments": "--username ${auth_player_name} --tweakClass net.minecraftforge.fml.common.launcher.FMLTweaker",
+			"libraries": [{"name": "net.minecraft:launchwrapper:1.12"}]
+		}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := installer.InstallLiteLoader(minecraft.Client, launcherDir, mcVersion, "1.12.2-SNAPSHOT", forgeVersion); err != nil {
+			t.Fatal(err)
+		}
+
+		versionName := VersionName(mcVersion, forgeVersion)
+		contents, err := ioutil.ReadFile(filepath.Join(launcherDir, "versions", versionName, versionName+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var version launcher.Version
+		if err := json.Unmarshal(contents, &version); err != nil {
+			t.Fatal(err)
+		}
+		if version.InheritsFrom != forgeVersion || version.Jar != "1.12.2" {
+			t.Errorf("unexpected version %s", contents)
+		}
+		if version.MinecraftArguments != "--username ${auth_player_name} --tweakClass net.minecraftforge.fml.common.launcher.FMLTweaker --tweakClass com.mumfrey.liteloader.launch.LiteLoaderTweaker" {
+			t.Errorf("unexpected arguments %s", version.MinecraftArguments)
+		}
+		if len(version.Libraries) != 2 || version.Libraries[1].Name != "org.ow2.asm:asm-all:5.2" {
+			t.Errorf("LaunchWrapper should only be inherited, got %s", contents)
+		}
+		if _, err := os.Stat(filepath.Join(launcherDir, "libraries", "com", "mumfrey", "liteloader", "1.12.2-SNAPSHOT", "liteloader-1.12.2-SNAPSHOT.jar")); err != nil {
+			t.Errorf("LiteLoader wasn't downloaded: %v", err)
+		}
+	}
+
+	// Server, as a mod of Minecraft Forge
+	{
+		serverDir := filepath.Join(dir, "server")
+		if err := installer.InstallLiteLoader(minecraft.Server, serverDir, mcVersion, "1.12.2-SNAPSHOT", forgeVersion); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(serverDir, "mods", "liteloader-1.12.2-SNAPSHOT.jar")); err != nil {
+			t.Errorf("LiteLoader wasn't installed as a mod: %v", err)
+		}
+
+		err := installer.InstallLiteLoader(minecraft.Server, serverDir, mcVersion, "1.12.2-SNAPSHOT", "")
+		if !errors.Is(err, ErrServerUnsupported) {
+			t.Errorf("expected servers without Forge to be unsupported, got %v", err)
+		}
+	}
+
+	// Unknown versions
+	if _, err := installer.GetBuild(context.Background(), mcVersion, "1.12.2_00"); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("expected an unknown version to fail, got %v", err)
+	}
+}
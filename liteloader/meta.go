@@ -0,0 +1,209 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package liteloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+const (
+	defaultLibrariesRoot = "https://libraries.minecraft.net/"
+)
+
+// Versions is LiteLoader's list of versions, for every version of
+// Minecraft it supports.
+type Versions struct {
+	Versions map[string]*GameVersion `json:"versions"`
+}
+
+// GameVersion lists the releases and snapshots of LiteLoader, for a version
+// of Minecraft.
+type GameVersion struct {
+	// The repository releases are published to
+	Repo *Repository `json:"repo"`
+
+	Artefacts *Stream `json:"artefacts"`
+	Snapshots *Stream `json:"snapshots"`
+}
+
+// Stream is either the releases or snapshots of LiteLoader, for a version
+// of Minecraft.
+type Stream struct {
+	// The repository the stream is published to, should it differ from that
+	// of releases
+	Repo *Repository `json:"repo"`
+
+	// The libraries needed by every build in the stream
+	Libraries []*Library `json:"libraries"`
+
+	// Every build of LiteLoader in the stream, by version - along with the
+	// latest build, as "latest".
+	LiteLoader map[string]*Artefact `json:"com.mumfrey:liteloader"`
+}
+
+// Repository is a Maven repository that LiteLoader is published to.
+type Repository struct {
+	Stream string `json:"stream"`
+	URL    string `json:"url"`
+}
+
+// Artefact is a build of LiteLoader.
+type Artefact struct {
+	Version    string `json:"version"`
+	File       string `json:"file"`
+	Stream     string `json:"stream"`
+	TweakClass string `json:"tweakClass"`
+
+	// The libraries needed by the build, should they differ from those of
+	// its stream
+	Libraries []*Library `json:"libraries"`
+}
+
+// Library is a library required by LiteLoader.
+type Library struct {
+	// The Maven artifact, for example net.minecraft:launchwrapper:1.12
+	Name string `json:"name"`
+
+	// The root of the Maven repository that hosts the library, should it
+	// not be hosted by Mojang
+	URL string `json:"url,omitempty"`
+}
+
+// Path gets the path of the library, relative to the libraries directory.
+func (l *Library) Path() (string, error) {
+	return util.MavenPath(l.Name)
+}
+
+// DownloadURL gets the URL to download the library from.
+func (l *Library) DownloadURL() (string, error) {
+	path, err := l.Path()
+	if err != nil {
+		return "", err
+	}
+	root := l.URL
+	if root == "" {
+		root = defaultLibrariesRoot
+	}
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+	return root + path, nil
+}
+
+// Build is a build of LiteLoader, along with where to download it from and
+// the libraries it needs.
+type Build struct {
+	Version string
+
+	// The tweaker LiteLoader is launched with, by LaunchWrapper
+	TweakClass string
+
+	// Where to download LiteLoader from
+	URL string
+
+	Libraries []*Library
+}
+
+// GetVersions gets LiteLoader's list of versions.
+func (i *Installer) GetVersions(ctx context.Context) (*Versions, error) {
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, i.VersionsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := util.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("liteloader: failed to get versions: %s", resp.Status)
+	}
+
+	var versions Versions
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, err
+	}
+	return &versions, nil
+}
+
+// GetBuild gets the given build of LiteLoader, for the given version of
+// Minecraft.
+func (i *Installer) GetBuild(ctx context.Context, mcVersion *minecraft.Version, version string) (*Build, error) {
+	versions, err := i.GetVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return versions.FindBuild(mcVersion, version)
+}
+
+// FindBuild finds the given build of LiteLoader, for the given version of
+// Minecraft.
+func (v *Versions) FindBuild(mcVersion *minecraft.Version, version string) (*Build, error) {
+	gameVersion, ok := v.Versions[mcVersion.String()]
+	if ok {
+		for _, stream := range []*Stream{gameVersion.Artefacts, gameVersion.Snapshots} {
+			if stream == nil {
+				continue
+			}
+			for key, artefact := range stream.LiteLoader {
+				if key == "latest" || artefact.Version != version {
+					continue
+				}
+				return newBuild(mcVersion, gameVersion, stream, artefact), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s for Minecraft %s", ErrVersionNotFound, version, mcVersion)
+}
+
+// Creates a build, from the given artefact of the stream.
+func newBuild(mcVersion *minecraft.Version, gameVersion *GameVersion, stream *Stream, artefact *Artefact) *Build {
+	repo := stream.Repo
+	if repo == nil {
+		repo = gameVersion.Repo
+	}
+	root := ""
+	if repo != nil {
+		root = repo.URL
+	}
+	if !strings.HasSuffix(root, "/") {
+		root += "/"
+	}
+
+	// Releases are published by Minecraft version, and snapshots as Maven
+	// snapshots
+	dir := mcVersion.String()
+	if strings.HasSuffix(artefact.Version, "-SNAPSHOT") {
+		dir = artefact.Version
+	}
+	file := artefact.File
+	if file == "" {
+		file = "liteloader-" + artefact.Version + ".jar"
+	}
+
+	libraries := artefact.Libraries
+	if len(libraries) == 0 {
+		libraries = stream.Libraries
+	}
+	tweakClass := artefact.TweakClass
+	if tweakClass == "" {
+		tweakClass = defaultTweakClass
+	}
+	return &Build{
+		Version:    artefact.Version,
+		TweakClass: tweakClass,
+		URL:        root + "com/mumfrey/liteloader/" + dir + "/" + file,
+		Libraries:  libraries,
+	}
+}
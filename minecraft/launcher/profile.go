@@ -6,16 +6,24 @@ package launcher
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/jamiemansfield/mcinstall/util"
 )
 
+var (
+	ErrProfileDoesntExist = errors.New("launcher: given profile doesn't exist")
+	ErrMalformedProfiles  = errors.New("launcher: launcher_profiles.json is malformed")
+)
+
 // Struct for quickly creating new profiles.
 type Profile struct {
 	Name    string `json:"name"`
@@ -23,10 +31,20 @@ type Profile struct {
 	GameDir string `json:"gameDir"`
 	Icon    string `json:"icon"`
 	Version string `json:"lastVersionId"`
+
+	// The arguments given to Java, such as the memory to use
+	JavaArgs string `json:"javaArgs,omitempty"`
 }
 
+// Guards launcher_profiles.json, so concurrent installs don't clobber
+// each others profiles.
+var profilesLock sync.Mutex
+
 // Installs the given profile to the Minecraft launcher.
 func InstallProfile(id string, profile *Profile) error {
+	profilesLock.Lock()
+	defer profilesLock.Unlock()
+
 	path := filepath.Join(GetLauncherDir(), "launcher_profiles.json")
 
 	data, err := ioutil.ReadFile(path)
@@ -51,10 +69,82 @@ func InstallProfile(id string, profile *Profile) error {
 	return encoder.Encode(&raw)
 }
 
+// Removes the profile of the given id from the Minecraft launcher.
+// Should the launcher not have the profile, ErrProfileDoesntExist is
+// returned.
+func RemoveProfile(id string) error {
+	profilesLock.Lock()
+	defer profilesLock.Unlock()
+
+	path := filepath.Join(GetLauncherDir(), "launcher_profiles.json")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw["profiles"] == nil {
+		return ErrProfileDoesntExist
+	}
+	profiles, ok := raw["profiles"].(map[string]interface{})
+	if !ok {
+		return ErrMalformedProfiles
+	}
+	if _, ok := profiles[id]; !ok {
+		return ErrProfileDoesntExist
+	}
+	delete(profiles, id)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(&raw)
+}
+
+// Gets the ids of the launcher profiles that use the given version.
+func GetProfilesUsingVersion(version string) ([]string, error) {
+	profilesLock.Lock()
+	defer profilesLock.Unlock()
+
+	data, err := ioutil.ReadFile(filepath.Join(GetLauncherDir(), "launcher_profiles.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		Profiles map[string]*Profile `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for id, profile := range raw.Profiles {
+		if profile.Version == version {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 // CreateIconFromURL creates a string that can be used within a Profile
 // as a profile icon, from a remote resource.
 func CreateIconFromURL(url string) (string, error) {
-	req, err := util.NewRequest(http.MethodGet, url, nil)
+	return CreateIconFromURLContext(context.Background(), url)
+}
+
+// See CreateIconFromURL
+func CreateIconFromURLContext(ctx context.Context, url string) (string, error) {
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return "", err
 	}
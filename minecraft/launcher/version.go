@@ -5,11 +5,15 @@
 package launcher
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/jamiemansfield/mcinstall/minecraft/manifest"
 	"github.com/jamiemansfield/mcinstall/util"
@@ -20,10 +24,14 @@ type Version struct {
 	ID           string            `json:"id"`
 	Type         string            `json:"type"`
 	InheritsFrom string            `json:"inheritsFrom"`
+	Jar          string            `json:"jar,omitempty"`
 	MainClass    string            `json:"mainClass,omitempty"`
 	Libraries    []*VersionLibrary `json:"libraries,omitempty"`
 	Downloads    struct {
 	} `json:"downloads"`
+
+	// Replaces the arguments of the inherited version, should it be given
+	MinecraftArguments string `json:"minecraftArguments,omitempty"`
 }
 
 type VersionLibrary struct {
@@ -33,11 +41,17 @@ type VersionLibrary struct {
 
 var (
 	ErrVersionDoesntExist = errors.New("launcher: given version doesn't exist")
+	ErrInvalidVersionName = errors.New("launcher: invalid version name")
 )
 
 // InstallClientVersion installs the given client version, to the given
 // launcher directory.
 func InstallClientVersion(launcherDir string, versionName string) error {
+	return InstallClientVersionContext(context.Background(), launcherDir, versionName)
+}
+
+// See InstallClientVersion
+func InstallClientVersionContext(ctx context.Context, launcherDir string, versionName string) error {
 	versionDir := filepath.Join(launcherDir, "versions", versionName)
 	versionJar := filepath.Join(versionDir, versionName+".jar")
 	versionJson := filepath.Join(versionDir, versionName+".json")
@@ -50,7 +64,7 @@ func InstallClientVersion(launcherDir string, versionName string) error {
 	}
 
 	// Get version information
-	versions, err := manifest.GetVersionManifest(nil)
+	versions, err := manifest.GetVersionManifestContext(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -69,27 +83,21 @@ func InstallClientVersion(launcherDir string, versionName string) error {
 		fmt.Println("Downloading " + versionName + " client jar...")
 
 		// Get full version
-		version, err := versionInfo.GetFull(nil)
-		if err != nil {
-			return err
-		}
-
-		// Create file
-		f, err := os.Create(versionJar)
+		version, err := versionInfo.GetFullContext(ctx, nil)
 		if err != nil {
 			return err
 		}
-		defer f.Close()
 
 		// Create request
-		req, err := util.NewRequest(http.MethodGet, version.Downloads.Client.URL, nil)
+		req, err := util.NewRequestWithContext(ctx, http.MethodGet, version.Downloads.Client.URL, nil)
 		if err != nil {
 			return err
 		}
 		req.Header.Add("Accepts", "*")
 
-		// Download file
-		if err := util.Download(f, req); err != nil {
+		// Download file, verifying it against Mojang's sha1 hash
+		client := version.Downloads.Client
+		if _, err := util.DownloadFile(req, versionJar, client.Sha1, int64(client.Size)); err != nil {
 			return err
 		}
 	}
@@ -106,7 +114,7 @@ func InstallClientVersion(launcherDir string, versionName string) error {
 		defer f.Close()
 
 		// Create request
-		req, err := util.NewRequest(http.MethodGet, versionInfo.URL, nil)
+		req, err := util.NewRequestWithContext(ctx, http.MethodGet, versionInfo.URL, nil)
 		if err != nil {
 			return err
 		}
@@ -120,3 +128,60 @@ func InstallClientVersion(launcherDir string, versionName string) error {
 
 	return nil
 }
+
+// RemoveVersion removes the given version from the given launcher
+// directory. Libraries used by the version are left in place, as they may
+// be shared with other versions.
+// The name must be that of a directory within the versions directory,
+// otherwise ErrInvalidVersionName is returned.
+func RemoveVersion(launcherDir string, versionName string) error {
+	if versionName == "" {
+		return ErrVersionDoesntExist
+	}
+	if versionName == "." || versionName == ".." || strings.ContainsAny(versionName, `/\`) {
+		return ErrInvalidVersionName
+	}
+
+	versionsDir := filepath.Join(launcherDir, "versions")
+	versionDir := filepath.Join(versionsDir, versionName)
+	if filepath.Dir(versionDir) != versionsDir {
+		return ErrInvalidVersionName
+	}
+	return os.RemoveAll(versionDir)
+}
+
+// GetVersionsInheritingFrom gets the versions, within the given launcher
+// directory, that inherit from the given version.
+func GetVersionsInheritingFrom(launcherDir string, versionName string) ([]string, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(launcherDir, "versions"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var versions []string
+	for _, info := range infos {
+		if !info.IsDir() || info.Name() == versionName {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(launcherDir, "versions", info.Name(), info.Name()+".json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var version Version
+		if err := json.Unmarshal(data, &version); err != nil {
+			// Not a version we can read, so can't be inheriting
+			continue
+		}
+		if version.InheritsFrom == versionName {
+			versions = append(versions, info.Name())
+		}
+	}
+	return versions, nil
+}
@@ -5,19 +5,29 @@
 package manifest
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
 	"github.com/jamiemansfield/mcinstall/util"
 )
 
+const (
+	VersionManifestURL = "https://launchermeta.mojang.com/mc/game/version_manifest.json"
+)
+
 func GetVersionManifest(httpClient *http.Client) (*VersionManifest, error) {
+	return GetVersionManifestContext(context.Background(), httpClient)
+}
+
+// See GetVersionManifest
+func GetVersionManifestContext(ctx context.Context, httpClient *http.Client) (*VersionManifest, error) {
 	if httpClient == nil {
-		httpClient = http.DefaultClient
+		httpClient = util.HTTPClient
 	}
 
 	// Create the request
-	req, err := util.NewRequest(http.MethodGet, "https://launchermeta.mojang.com/mc/game/version_manifest.json", nil)
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, VersionManifestURL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -37,12 +47,17 @@ func GetVersionManifest(httpClient *http.Client) (*VersionManifest, error) {
 }
 
 func (v *VersionManifestVersion) GetFull(httpClient *http.Client) (*Version, error) {
+	return v.GetFullContext(context.Background(), httpClient)
+}
+
+// See GetFull
+func (v *VersionManifestVersion) GetFullContext(ctx context.Context, httpClient *http.Client) (*Version, error) {
 	if httpClient == nil {
-		httpClient = http.DefaultClient
+		httpClient = util.HTTPClient
 	}
 
 	// Create the request
-	req, err := util.NewRequest(http.MethodGet, v.URL, nil)
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, v.URL, nil)
 	if err != nil {
 		return nil, err
 	}
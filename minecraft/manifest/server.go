@@ -0,0 +1,48 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package manifest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jamiemansfield/mcinstall/util"
+)
+
+var (
+	ErrVersionNotFound = errors.New("manifest: version not found")
+	ErrNoServer        = errors.New("manifest: version has no server")
+)
+
+// DownloadServer downloads the server jar of the given version of
+// Minecraft to the given path, verifying it against Mojang's sha1 hash.
+func DownloadServer(ctx context.Context, version string, path string) error {
+	versions, err := GetVersionManifestContext(ctx, nil)
+	if err != nil {
+		return err
+	}
+	versionInfo := versions.FindVersion(version)
+	if versionInfo == nil {
+		return ErrVersionNotFound
+	}
+	full, err := versionInfo.GetFullContext(ctx, nil)
+	if err != nil {
+		return err
+	}
+	server := full.Downloads.Server
+	if server == nil {
+		return ErrNoServer
+	}
+
+	req, err := util.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/java-archive")
+
+	_, err = util.DownloadFile(req, path, server.Sha1, int64(server.Size))
+	return err
+}
@@ -19,6 +19,7 @@ type Version struct {
 		Server         *VersionDownload `json:"server"`
 		ServerMappings *VersionDownload `json:"server_mappings"`
 	} `json:"downloads"`
+	Libraries []*Library `json:"libraries"`
 }
 
 type VersionDownload struct {
@@ -26,3 +27,18 @@ type VersionDownload struct {
 	Size int    `json:"size"`
 	URL  string `json:"url"`
 }
+
+type Library struct {
+	Name      string `json:"name"`
+	Downloads struct {
+		Artifact    *LibraryDownload            `json:"artifact"`
+		Classifiers map[string]*LibraryDownload `json:"classifiers"`
+	} `json:"downloads"`
+}
+
+type LibraryDownload struct {
+	Path string `json:"path"`
+	Sha1 string `json:"sha1"`
+	Size int    `json:"size"`
+	URL  string `json:"url"`
+}
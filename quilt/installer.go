@@ -0,0 +1,86 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package quilt
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/jamiemansfield/mcinstall/fabric"
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+const (
+	defaultMetaRoot = "https://meta.quiltmc.org/"
+
+	// The jar that servers are launched with
+	ServerLaunchJar = "quilt-server-launch.jar"
+)
+
+// QuiltLoader is Quilt Loader, which as a fork of Fabric Loader is
+// installed in the same way.
+var QuiltLoader = &fabric.Loader{
+	Name:              "Quilt Loader",
+	Group:             "org.quiltmc",
+	Artifact:          "quilt-loader",
+	MetaVersion:       "v3",
+	ServerLaunchJar:   ServerLaunchJar,
+	LauncherMainClass: "org.quiltmc.loader.impl.launch.server.QuiltServerLauncher",
+}
+
+type Installer struct {
+	// The URL to Quilt's meta API, or a mirror.
+	MetaRoot *url.URL
+}
+
+// NewInstaller returns a new Installer to use for installing Quilt Loader.
+func NewInstaller() *Installer {
+	metaRoot, _ := url.Parse(defaultMetaRoot)
+
+	return &Installer{
+		MetaRoot: metaRoot,
+	}
+}
+
+// VersionName gets the name of the launcher version for the given version
+// of Quilt Loader, such as quilt-loader-0.17.6-1.19.2.
+func VersionName(mcVersion *minecraft.Version, loaderVersion string) string {
+	return QuiltLoader.VersionName(mcVersion, loaderVersion)
+}
+
+// Installs Quilt Loader to the given destination, for the given target.
+// If the target is Server, the destination will be the root directory of
+// the server; if the target is Client, the destination will be the
+// launcher's root directory.
+func (i *Installer) InstallQuilt(target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, loaderVersion string) error {
+	return i.InstallQuiltContext(context.Background(), target, dest, mcVersion, loaderVersion)
+}
+
+// See InstallQuilt
+// Should the context be cancelled, any in-progress download will be
+// aborted.
+func (i *Installer) InstallQuiltContext(ctx context.Context, target minecraft.InstallTarget, dest string, mcVersion *minecraft.Version, loaderVersion string) error {
+	return i.fabric().InstallFabricContext(ctx, target, dest, mcVersion, loaderVersion)
+}
+
+// ProfileURL gets the URL of the profile of the given version of Quilt
+// Loader, for the given install target.
+func (i *Installer) ProfileURL(target minecraft.InstallTarget, mcVersion *minecraft.Version, loaderVersion string) (*url.URL, error) {
+	return i.fabric().ProfileURL(target, mcVersion, loaderVersion)
+}
+
+// GetProfile gets the profile of the given version of Quilt Loader, for
+// the given install target.
+func (i *Installer) GetProfile(ctx context.Context, target minecraft.InstallTarget, mcVersion *minecraft.Version, loaderVersion string) (*fabric.Profile, error) {
+	return i.fabric().GetProfile(ctx, target, mcVersion, loaderVersion)
+}
+
+// Gets a Fabric installer, that installs Quilt Loader from Quilt's meta API.
+func (i *Installer) fabric() *fabric.Installer {
+	return &fabric.Installer{
+		MetaRoot: i.MetaRoot,
+		Loader:   QuiltLoader,
+	}
+}
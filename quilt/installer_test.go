@@ -0,0 +1,92 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package quilt
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jamiemansfield/mcinstall/minecraft"
+)
+
+func TestInstallQuilt(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/versions/loader/1.19.2/0.17.6/profile/json":
+			fmt.Fprintf(w, `{"id": "quilt-loader-0.17.6-1.19.2", "inheritsFrom": "1.19.2", "mainClass": "org.quiltmc.loader.impl.launch.knot.KnotClient", "libraries": [
+				{"name": "org.quiltmc:quilt-loader:0.17.6", "url": "%s/maven/"}
+			]}`, server.URL)
+		case "/v3/versions/loader/1.19.2/0.17.6/server/json":
+			fmt.Fprintf(w, `{"id": "quilt-loader-0.17.6-1.19.2", "mainClass": "org.quiltmc.loader.impl.launch.knot.KnotServer", "libraries": [
+				{"name": "org.quiltmc:quilt-loader:0.17.6", "url": "%s/maven/"}
+			]}`, server.URL)
+		case "/maven/org/quiltmc/quilt-loader/0.17.6/quilt-loader-0.17.6.jar":
+			fmt.Fprint(w, "jar")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "quiltinstall")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	installer := NewInstaller()
+	installer.MetaRoot, _ = url.Parse(server.URL + "/")
+	mcVersion, _ := minecraft.ParseVersion("1.19.2")
+
+	// Client
+	launcherDir := filepath.Join(dir, "launcher")
+	if err := installer.InstallQuilt(minecraft.Client, launcherDir, mcVersion, "0.17.6"); err != nil {
+		t.Fatal(err)
+	}
+	versionName := VersionName(mcVersion, "0.17.6")
+	if versionName != "quilt-loader-0.17.6-1.19.2" {
+		t.Errorf("unexpected version name %s", versionName)
+	}
+	if _, err := os.Stat(filepath.Join(launcherDir, "versions", versionName, versionName+".json")); err != nil {
+		t.Errorf("version json wasn't written: %v", err)
+	}
+
+	// Server, where the vanilla server is already in place
+	serverDir := filepath.Join(dir, "server")
+	if err := os.MkdirAll(serverDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(serverDir, "server.jar"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := installer.InstallQuilt(minecraft.Server, serverDir, mcVersion, "0.17.6"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(filepath.Join(serverDir, ServerLaunchJar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	found := false
+	for _, file := range r.File {
+		if file.Name == "quilt-server-launch.properties" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("the server launcher should read quilt-server-launch.properties")
+	}
+	if _, err := os.Stat(filepath.Join(serverDir, "libraries", "org", "quiltmc", "quilt-loader", "0.17.6", "quilt-loader-0.17.6.jar")); err != nil {
+		t.Errorf("library wasn't downloaded: %v", err)
+	}
+}
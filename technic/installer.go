@@ -6,6 +6,8 @@ package technic
 
 import (
 	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,6 +25,12 @@ import (
 	"github.com/jamiemansfield/mcinstall/util"
 )
 
+const (
+	// The directory, within the destination, that downloads are kept
+	// until they have been extracted.
+	dataDir = ".technicinstall"
+)
+
 // Installs the given pack version to the destination, with the
 // appropriate files for that install target.
 func InstallPackVersion(dest string, pack *platform.Modpack, version string) error {
@@ -37,7 +45,7 @@ func InstallPackVersion(dest string, pack *platform.Modpack, version string) err
 
 	// Download the files
 	if pack.Solder != "" {
-		client := solder.NewClient(nil)
+		client := solder.NewClient(util.HTTPClient)
 		solderUrl, err := url.Parse(pack.Solder)
 		if err != nil {
 			return err
@@ -55,7 +63,8 @@ func InstallPackVersion(dest string, pack *platform.Modpack, version string) err
 		for i, mod := range build.Mods {
 			fmt.Printf("[%d / %d] Installing %s...\n", i+1, total, mod.Name)
 
-			err := downloadAndExtractZip(mod.URL, dest)
+			// Solder mod URLs are versioned, so can be cached
+			err := downloadAndExtractZip(mod.URL, dest, true)
 			if err != nil {
 				return err
 			}
@@ -71,7 +80,7 @@ func InstallPackVersion(dest string, pack *platform.Modpack, version string) err
 		}
 		fmt.Printf("Installing from direct download...\n")
 
-		err = downloadAndExtractZip(pack.URL, dest)
+		err = downloadAndExtractZip(pack.URL, dest, false)
 		if err != nil {
 			return err
 		}
@@ -253,7 +262,10 @@ func InstallPackVersion(dest string, pack *platform.Modpack, version string) err
 	return launcher.InstallProfile(pack.Name, profile)
 }
 
-func downloadAndExtractZip(url string, dest string) error {
+// Downloads the zip at the given URL, and extracts it to the destination.
+// Should the URL always refer to the same file, it can be taken from the
+// download cache.
+func downloadAndExtractZip(url string, dest string, cacheable bool) error {
 	// GET the file
 	req, err := util.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
@@ -261,24 +273,39 @@ func downloadAndExtractZip(url string, dest string) error {
 	}
 	req.Header.Set("Accept", "*")
 
-	// Download to temporary file
-	tmp, err := util.DownloadTemp(req, "*.zip")
+	// Download within the destination, so an interrupted download can be
+	// resumed by the next install
+	hasher := sha1.New()
+	hasher.Write([]byte(url))
+	name := hex.EncodeToString(hasher.Sum(nil))
+	path := filepath.Join(dest, dataDir, name+".zip")
+	if cacheable && util.DownloadCache != nil {
+		if err := util.DownloadCache.DownloadURL(req, path); err != nil {
+			return err
+		}
+	} else if _, err := util.DownloadResumable(req, path, filepath.Join(dest, dataDir, name+util.PartSuffix), "", 0); err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	tmp, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		tmp.Close()
-		os.Remove(tmp.Name())
-	}()
+	defer tmp.Close()
 	tmpInfo, err := tmp.Stat()
 	if err != nil {
 		return err
 	}
 
-	// Extract zip file
+	// Extract zip file - as we have no checksum to verify a resumed
+	// download against, this is our check that it isn't corrupt
 	zipFile, err := zip.NewReader(tmp, tmpInfo.Size())
 	if err != nil {
-		return err
+		if cacheable && util.DownloadCache != nil {
+			util.DownloadCache.ForgetURL(url)
+		}
+		return fmt.Errorf("technic: %s is corrupt: %w", url, err)
 	}
 	return util.ExtractZipFileToDisk(zipFile, dest)
 }
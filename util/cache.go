@@ -0,0 +1,377 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// The directory, within the cache, that files are kept - named by
+	// their sha1 hash.
+	cacheObjectsDir = "objects"
+
+	// The directory, within the cache, that files without a known hash
+	// are kept - named by the sha1 hash of their URL.
+	cacheURLsDir = "urls"
+
+	// The directory, within the cache, that partial downloads are kept.
+	cachePartsDir = "parts"
+)
+
+var (
+	// The cache consulted by downloads of files with a known sha1 hash,
+	// nil should downloads not be cached.
+	DownloadCache *Cache
+)
+
+// Cache is a content-addressable store of downloaded files, that can be
+// shared between installs. Files are placed into installs by reflinking
+// them where the filesystem supports it, and copying them otherwise.
+// Immutable artifacts (jars and zips, outside of any config directory) may
+// instead be hardlinked, should reflinks not be supported - anything the
+// player is expected to edit never shares its inode with the cache.
+// As a hardlinked file can still be changed by the player, files are
+// verified against their hash whenever they are taken from the cache.
+type Cache struct {
+	Dir string
+
+	// Locks on the files being downloaded, so the same file isn't
+	// downloaded twice at once - removed once they are no longer held
+	locks   map[string]*cacheLock
+	locksMu sync.Mutex
+}
+
+type cacheLock struct {
+	sync.Mutex
+
+	// The number of downloads holding, or waiting on, the lock
+	holders int
+}
+
+// CacheStats describes the files held by a cache.
+type CacheStats struct {
+	Files  int
+	Size   int64
+	Oldest time.Time
+	Newest time.Time
+}
+
+// NewCache returns a Cache, kept in the given directory.
+func NewCache(dir string) *Cache {
+	return &Cache{
+		Dir: dir,
+	}
+}
+
+// Gets the directory the cache is kept in by default, within the user's
+// cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mcinstall"), nil
+}
+
+// Download downloads the file with the given sha1 hash to the given path,
+// by way of the cache. Should the cache already hold the file, nothing is
+// downloaded - otherwise the file is downloaded (and verified) as with
+// DownloadResumable.
+// The sha1 hash of the file is returned.
+func (c *Cache) Download(req *http.Request, path string, expectedSha1 string, expectedSize int64) (string, error) {
+	unlock := c.lock(expectedSha1)
+	defer unlock()
+
+	object := c.objectPath(cacheObjectsDir, expectedSha1)
+	if hash, err := HashFile(object); err == nil {
+		if hash == expectedSha1 {
+			return hash, c.place(object, path)
+		}
+
+		// The cached copy has been changed, or corrupted, since it was
+		// downloaded - so replace it
+		if err := os.Remove(object); err != nil {
+			return "", err
+		}
+	}
+
+	hash, err := c.download(req, object, expectedSha1, expectedSha1, expectedSize)
+	if err != nil {
+		return "", err
+	}
+	return hash, c.place(object, path)
+}
+
+// DownloadURL downloads the file at the request's URL to the given path,
+// by way of the cache. As the file's hash isn't known, the file is cached
+// by its URL - so this should only be used for URLs that always refer to
+// the same file.
+func (c *Cache) DownloadURL(req *http.Request, path string) error {
+	key := urlKey(req.URL.String())
+	unlock := c.lock(key)
+	defer unlock()
+
+	object := c.objectPath(cacheURLsDir, key)
+	if _, err := os.Stat(object); err != nil {
+		if _, err := c.download(req, object, key, "", 0); err != nil {
+			return err
+		}
+	}
+	return c.place(object, path)
+}
+
+// ForgetURL removes the file cached for the given URL, should it turn out
+// to be corrupt.
+func (c *Cache) ForgetURL(url string) error {
+	err := os.Remove(c.objectPath(cacheURLsDir, urlKey(url)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Stats describes the files currently held by the cache.
+func (c *Cache) Stats() (*CacheStats, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	return statEntries(entries), nil
+}
+
+// Prune removes files from the cache, that were last used longer than
+// maxAge ago - and then the least recently used files, until the cache is
+// no larger than maxSize. Should either be 0, it isn't used to prune the
+// cache.
+// The files that were removed are described.
+func (c *Cache) Prune(maxSize int64, maxAge time.Duration) (*CacheStats, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Oldest first
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].ModTime().Before(entries[b].ModTime())
+	})
+
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size()
+	}
+
+	var removed []*cacheEntry
+	for _, entry := range entries {
+		tooOld := maxAge > 0 && time.Since(entry.ModTime()) > maxAge
+		tooLarge := maxSize > 0 && size > maxSize
+		if !tooOld && !tooLarge {
+			continue
+		}
+
+		if err := os.Remove(entry.path); err != nil {
+			return nil, err
+		}
+		size -= entry.Size()
+		removed = append(removed, entry)
+	}
+
+	return statEntries(removed), nil
+}
+
+type cacheEntry struct {
+	os.FileInfo
+	path string
+}
+
+func (c *Cache) entries() ([]*cacheEntry, error) {
+	var entries []*cacheEntry
+	err := filepath.Walk(c.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			entries = append(entries, &cacheEntry{
+				FileInfo: info,
+				path:     path,
+			})
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func statEntries(entries []*cacheEntry) *CacheStats {
+	stats := &CacheStats{}
+	for _, entry := range entries {
+		stats.Files++
+		stats.Size += entry.Size()
+		if stats.Oldest.IsZero() || entry.ModTime().Before(stats.Oldest) {
+			stats.Oldest = entry.ModTime()
+		}
+		if entry.ModTime().After(stats.Newest) {
+			stats.Newest = entry.ModTime()
+		}
+	}
+	return stats
+}
+
+func (c *Cache) objectPath(dir string, key string) string {
+	if len(key) < 2 {
+		return filepath.Join(c.Dir, dir, key)
+	}
+	return filepath.Join(c.Dir, dir, key[:2], key)
+}
+
+func urlKey(url string) string {
+	hasher := sha1.New()
+	hasher.Write([]byte(url))
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func (c *Cache) lock(key string) func() {
+	c.locksMu.Lock()
+	if c.locks == nil {
+		c.locks = map[string]*cacheLock{}
+	}
+	l := c.locks[key]
+	if l == nil {
+		l = &cacheLock{}
+		c.locks[key] = l
+	}
+	l.holders++
+	c.locksMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		c.locksMu.Lock()
+		l.holders--
+		if l.holders == 0 {
+			delete(c.locks, key)
+		}
+		c.locksMu.Unlock()
+	}
+}
+
+// Downloads the file with the given key into the cache, resuming any
+// partial download. As the cache may be shared with other processes, the
+// partial download is first claimed by renaming it to a name unique to
+// this download - so two processes never write to the same file. Should
+// the download fail, what was downloaded is put back to be resumed later.
+func (c *Cache) download(req *http.Request, object string, key string, expectedSha1 string, expectedSize int64) (string, error) {
+	partPath := c.objectPath(cachePartsDir, key)
+	if err := os.MkdirAll(filepath.Dir(partPath), os.ModePerm); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(partPath), key+".*")
+	if err != nil {
+		return "", err
+	}
+	claimed := f.Name()
+	f.Close()
+	if err := os.Rename(partPath, claimed); err != nil && !os.IsNotExist(err) {
+		os.Remove(claimed)
+		return "", err
+	}
+
+	hash, err := downloadResumable(req, object, claimed, expectedSha1, expectedSize)
+	if err != nil {
+		if _, statErr := os.Stat(claimed); statErr == nil {
+			os.Rename(claimed, partPath)
+		}
+		return "", err
+	}
+	return hash, nil
+}
+
+// Places the given cached file at the given path, by reflinking,
+// hardlinking (see isImmutable) or copying it.
+func (c *Cache) place(object string, path string) error {
+	// Record that the file has been used, for pruning
+	now := time.Now()
+	if err := os.Chtimes(object, now, now); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if reflink(object, path) == nil {
+		return nil
+	}
+	if isImmutable(path) && os.Link(object, path) == nil {
+		return nil
+	}
+	return copyFile(object, path)
+}
+
+// Determines whether the file at the given path is an artifact that is
+// never edited in place, and so can safely share its inode with the cache.
+// Configuration, and anything else that may be edited (or merged) by the
+// player, must be a copy - or changes would corrupt the cached file, and
+// the files of every other install sharing it.
+func isImmutable(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(filepath.Dir(path)), "/") {
+		if part == "config" {
+			return false
+		}
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jar", ".zip", ".litemod":
+		return true
+	default:
+		return false
+	}
+}
+
+// CopyFile copies the file at the given path, to the given destination,
+// by reflinking it where the filesystem supports it.
+func CopyFile(src string, dst string) error {
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if reflink(src, dst) == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,181 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, "hello")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "utilcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := NewCache(filepath.Join(dir, "cache"))
+
+	download := func(name string) {
+		req, err := NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if _, err := cache.Download(req, path, helloSha1, 5); err != nil {
+			t.Fatal(err)
+		}
+		if contents, err := ioutil.ReadFile(path); err != nil || string(contents) != "hello" {
+			t.Errorf("expected hello in %s, got %q (%v)", name, contents, err)
+		}
+	}
+
+	// The second install should be taken from the cache
+	download("first/hello.txt")
+	download("second/hello.txt")
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	// Changing an installed file mustn't affect later installs
+	if err := ioutil.WriteFile(filepath.Join(dir, "first", "hello.txt"), []byte("world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	download("third/hello.txt")
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 1 || stats.Size != 5 {
+		t.Errorf("expected 1 file of 5 bytes, got %d files of %d bytes", stats.Files, stats.Size)
+	}
+
+	removed, err := cache.Prune(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed.Files != 1 {
+		t.Errorf("expected 1 file to be pruned, got %d", removed.Files)
+	}
+	download("fourth/hello.txt")
+}
+
+func TestCachePlace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "utilcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := NewCache(filepath.Join(dir, "cache"))
+
+	object := cache.objectPath(cacheObjectsDir, helloSha1)
+	if err := os.MkdirAll(filepath.Dir(object), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(object, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files the player may edit must never share the cache's inode
+	for _, name := range []string{"hello.cfg", "config/hello.jar", "scripts/hello.zs"} {
+		path := filepath.Join(dir, "install", filepath.FromSlash(name))
+		if err := cache.place(object, path); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("world"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if contents, err := ioutil.ReadFile(object); err != nil || string(contents) != "hello" {
+			t.Errorf("editing %s changed the cached file to %q (%v)", name, contents, err)
+		}
+	}
+
+	// Whereas mods may be hardlinked
+	path := filepath.Join(dir, "install", "mods", "hello.jar")
+	if err := cache.place(object, path); err != nil {
+		t.Fatal(err)
+	}
+	if contents, err := ioutil.ReadFile(path); err != nil || string(contents) != "hello" {
+		t.Errorf("expected hello in mods/hello.jar, got %q (%v)", contents, err)
+	}
+}
+
+func TestCacheDownloadConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader("hello"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "utilcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := NewCache(filepath.Join(dir, "cache"))
+
+	// Leave a partial download behind, as an interrupted install would
+	partPath := cache.objectPath(cachePartsDir, helloSha1)
+	if err := os.MkdirAll(filepath.Dir(partPath), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(partPath, []byte("he"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for j := 0; j < 4; j++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			req, err := NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			path := filepath.Join(dir, fmt.Sprintf("install%d", j), "hello.txt")
+			if _, err := cache.Download(req, path, helloSha1, 5); err != nil {
+				t.Error(err)
+			}
+		}(j)
+	}
+	wg.Wait()
+
+	// The partial download should have been resumed, once
+	if len(ranges) != 1 || ranges[0] != "bytes=2-" {
+		t.Errorf("expected a single request for bytes=2-, got %q", ranges)
+	}
+
+	// Nothing should be left behind
+	if len(cache.locks) != 0 {
+		t.Errorf("expected no locks to be held, got %d", len(cache.locks))
+	}
+	parts, err := ioutil.ReadDir(filepath.Dir(partPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 0 {
+		t.Errorf("expected no partial downloads, got %d", len(parts))
+	}
+}
@@ -5,35 +5,277 @@
 package util
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// The number of times a download is attempted, before giving up.
+	downloadAttempts = 3
+
+	// The suffix given to partial downloads.
+	PartSuffix = ".part"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("util: download doesn't match its sha1 hash")
+	ErrSizeMismatch     = errors.New("util: download doesn't match its size")
 )
 
 // Downloads the file, copying it to the given writer.
+// The download can be cancelled using the request's context.
 func Download(dst io.Writer, req *http.Request) error {
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := HTTPClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	// Don't save an error page in place of the file
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("util: failed to download %s: %s", req.URL, resp.Status)
+	}
+
 	_, err = io.Copy(dst, resp.Body)
 	return err
 }
 
+// Downloads the file, copying it to the given writer - hashing it as it
+// streams. Should the sha1 hash or size be unknown, they should be given as
+// "" and 0 respectively.
+// The sha1 hash of the downloaded file is returned, should it match.
+func DownloadVerified(dst io.Writer, req *http.Request, expectedSha1 string, expectedSize int64) (string, error) {
+	hasher := sha1.New()
+	counter := &countingWriter{}
+	if err := Download(io.MultiWriter(dst, hasher, counter), req); err != nil {
+		return "", err
+	}
+
+	if expectedSize > 0 && counter.n != expectedSize {
+		return "", fmt.Errorf("%w: %s was %d bytes, expected %d", ErrSizeMismatch, req.URL, counter.n, expectedSize)
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if expectedSha1 != "" && hash != expectedSha1 {
+		return "", fmt.Errorf("%w: %s was %s, expected %s", ErrChecksumMismatch, req.URL, hash, expectedSha1)
+	}
+	return hash, nil
+}
+
+// Downloads the file to the given path, verifying it as with
+// DownloadVerified. Should the download fail, it is retried - and should
+// it be interrupted, it is resumed from path + PartSuffix the next time
+// the file is downloaded.
+// The sha1 hash of the downloaded file is returned.
+func DownloadFile(req *http.Request, path string, expectedSha1 string, expectedSize int64) (string, error) {
+	return DownloadResumable(req, path, path+PartSuffix, expectedSha1, expectedSize)
+}
+
+// Downloads the file to the given path, by way of the given partial
+// download. Should the partial download already exist, from an interrupted
+// download, only what remains of the file is requested - using a Range
+// request, should the server support them.
+// Once complete, the whole file is verified as with DownloadVerified and
+// moved into place. Should it not match, the partial download is removed
+// and the download is retried.
+// Should the download cache be enabled, and the sha1 hash known, the file
+// is downloaded by way of the cache instead.
+// The sha1 hash of the downloaded file is returned.
+func DownloadResumable(req *http.Request, path string, partPath string, expectedSha1 string, expectedSize int64) (string, error) {
+	if DownloadCache != nil && isSha1(expectedSha1) {
+		return DownloadCache.Download(req, path, expectedSha1, expectedSize)
+	}
+	return downloadResumable(req, path, partPath, expectedSha1, expectedSize)
+}
+
+func downloadResumable(req *http.Request, path string, partPath string, expectedSha1 string, expectedSize int64) (string, error) {
+	var hash string
+	err := retryDownload(req, func() error {
+		var err error
+		hash, err = resumeDownload(req, partPath, expectedSha1, expectedSize)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+func resumeDownload(req *http.Request, partPath string, expectedSha1 string, expectedSize int64) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(partPath), os.ModePerm); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(partPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// Hash what has already been downloaded, leaving us at the end of
+	// the file to carry on from
+	hasher := sha1.New()
+	offset, err := io.Copy(hasher, f)
+	if err != nil {
+		return "", err
+	}
+
+	// An interrupted install may have finished the download, without
+	// moving it into place
+	if offset == 0 || expectedSize <= 0 || offset < expectedSize {
+		n, err := downloadRemaining(f, hasher, req, offset)
+		if err == errRangeNotSatisfiable || err == errRangeNotSupported {
+			// Start over
+			if err := f.Truncate(0); err != nil {
+				return "", err
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return "", err
+			}
+			hasher.Reset()
+			offset = 0
+			n, err = downloadRemaining(f, hasher, req, 0)
+		}
+		if err != nil {
+			return "", err
+		}
+		offset += n
+	}
+
+	// Verify the whole file, including what was resumed
+	var mismatch error
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if expectedSize > 0 && offset != expectedSize {
+		mismatch = fmt.Errorf("%w: %s was %d bytes, expected %d", ErrSizeMismatch, req.URL, offset, expectedSize)
+	} else if expectedSha1 != "" && hash != expectedSha1 {
+		mismatch = fmt.Errorf("%w: %s was %s, expected %s", ErrChecksumMismatch, req.URL, hash, expectedSha1)
+	}
+	if mismatch != nil {
+		f.Close()
+		os.Remove(partPath)
+		return "", mismatch
+	}
+	return hash, nil
+}
+
+var (
+	errRangeNotSatisfiable = errors.New("util: range not satisfiable")
+	errRangeNotSupported   = errors.New("util: range not supported")
+)
+
+// Downloads the remainder of the file, from the given offset, writing it
+// to both the file and hasher.
+func downloadRemaining(f io.Writer, hasher io.Writer, req *http.Request, offset int64) (int64, error) {
+	if offset > 0 {
+		req = req.Clone(req.Context())
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		return 0, errRangeNotSatisfiable
+	case offset > 0 && resp.StatusCode == http.StatusOK:
+		// The server has sent the whole file
+		return 0, errRangeNotSupported
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return 0, errRangeNotSupported
+		}
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return 0, fmt.Errorf("util: failed to download %s: %s", req.URL, resp.Status)
+	}
+
+	return io.Copy(io.MultiWriter(f, hasher), resp.Body)
+}
+
+// Downloads the file into memory, verifying it as with DownloadVerified.
+// Should the download fail, it is retried.
+func DownloadBytes(req *http.Request, expectedSha1 string, expectedSize int64) ([]byte, error) {
+	var buf bytes.Buffer
+	err := retryDownload(req, func() error {
+		buf.Reset()
+		_, err := DownloadVerified(&buf, req, expectedSha1, expectedSize)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // Downloads the file, copying it to the given writer.
 // The temporary file should be removed after usage.
 func DownloadTemp(req *http.Request, pattern string) (*os.File, error) {
+	return DownloadTempVerified(req, pattern, "", 0)
+}
+
+// Downloads the file to a temporary file, verifying it as with
+// DownloadFile.
+// The temporary file should be removed after usage.
+func DownloadTempVerified(req *http.Request, pattern string, expectedSha1 string, expectedSize int64) (*os.File, error) {
 	file, err := ioutil.TempFile("", pattern)
 	if err != nil {
 		return nil, err
 	}
+	file.Close()
 
-	if err := Download(file, req); err != nil {
+	if _, err := DownloadFile(req, file.Name(), expectedSha1, expectedSize); err != nil {
+		os.Remove(file.Name())
+		os.Remove(file.Name() + PartSuffix)
 		return nil, err
 	}
+	return os.Open(file.Name())
+}
 
-	return file, nil
+// Makes the given download attempt, retrying should it fail - unless the
+// request has been cancelled.
+func retryDownload(req *http.Request, attempt func() error) error {
+	var err error
+	for j := 0; j < downloadAttempts; j++ {
+		if err = attempt(); err == nil {
+			return nil
+		}
+		if req.Context().Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// Whether the given string is a hex encoded sha1 hash.
+func isSha1(s string) bool {
+	if len(s) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
 }
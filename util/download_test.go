@@ -0,0 +1,111 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	helloSha1 = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d" // hello
+)
+
+func TestDownloadFile(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		// Fail the first request, as a flaky CDN might
+		if r.URL.Path == "/flaky" && requests > 1 {
+			fmt.Fprint(w, "hello")
+		} else {
+			fmt.Fprint(w, "<html>error</html>")
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "utildownload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hello.txt")
+
+	req, err := NewRequest(http.MethodGet, server.URL+"/flaky", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := DownloadFile(req, path, helloSha1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != helloSha1 || requests != 2 {
+		t.Errorf("expected %s after 2 requests, got %s after %d", helloSha1, hash, requests)
+	}
+
+	requests = 0
+	path = filepath.Join(dir, "broken.txt")
+	req, err = NewRequest(http.MethodGet, server.URL+"/broken", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DownloadFile(req, path, helloSha1, 0); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+	if requests != downloadAttempts {
+		t.Errorf("expected %d attempts, got %d", downloadAttempts, requests)
+	}
+	if _, err := os.Stat(path + PartSuffix); !os.IsNotExist(err) {
+		t.Error("failed download should have been removed")
+	}
+}
+
+func TestDownloadFileResume(t *testing.T) {
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "hello.txt", time.Time{}, strings.NewReader("hello"))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "utildownload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hello.txt")
+
+	// Leave behind part of the file, as an interrupted download would
+	if err := ioutil.WriteFile(path+PartSuffix, []byte("he"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := DownloadFile(req, path, helloSha1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != helloSha1 {
+		t.Errorf("expected %s, got %s", helloSha1, hash)
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=2-" {
+		t.Errorf("expected only the remainder of the file to be requested, got %v", ranges)
+	}
+	if contents, err := ioutil.ReadFile(path); err != nil || string(contents) != "hello" {
+		t.Errorf("expected hello, got %q (%v)", contents, err)
+	}
+}
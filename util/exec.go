@@ -5,6 +5,7 @@
 package util
 
 import (
+	"context"
 	"os"
 	"os/exec"
 )
@@ -12,7 +13,15 @@ import (
 // Runs the given program, with given arguments, outputting to the
 // console of this program.
 func RunCommand(name string, arg ...string) error {
-	cmd := exec.Command(name, arg...)
+	return RunCommandContext(context.Background(), name, arg...)
+}
+
+// Runs the given program, with given arguments, outputting to the
+// console of this program.
+// The process will be killed should the context be done before the
+// program exits.
+func RunCommandContext(ctx context.Context, name string, arg ...string) error {
+	cmd := exec.CommandContext(ctx, name, arg...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -5,6 +5,7 @@
 package util
 
 import (
+	"context"
 	"io"
 	"net/http"
 )
@@ -13,10 +14,49 @@ const (
 	UserAgent = "mcinstall/0.1.0"
 )
 
+var (
+	// The client used for all of mcinstall's requests.
+	HTTPClient = &http.Client{
+		Transport: &contextTransport{},
+	}
+)
+
+type transportKey struct{}
+
+// WithTransport returns a copy of the given context, so that requests made
+// with it through HTTPClient are served by the given transport - rather
+// than the network.
+func WithTransport(ctx context.Context, transport http.RoundTripper) context.Context {
+	return context.WithValue(ctx, transportKey{}, transport)
+}
+
+// Serves requests with the transport of their context (see WithTransport),
+// or with its own transport otherwise.
+type contextTransport struct {
+	// The transport to make requests with, http.DefaultTransport if nil
+	transport http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transport, ok := req.Context().Value(transportKey{}).(http.RoundTripper); ok {
+		return transport.RoundTrip(req)
+	}
+	if t.transport == nil {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return t.transport.RoundTrip(req)
+}
+
 // See http.NewRequest
 // Populates the Header with our User-Agent
 func NewRequest(method, url string, body io.Reader) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, body)
+	return NewRequestWithContext(context.Background(), method, url, body)
+}
+
+// See http.NewRequestWithContext
+// Populates the Header with our User-Agent
+func NewRequestWithContext(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
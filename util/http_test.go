@@ -0,0 +1,73 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	// As set up by UseMirrors
+	client := &http.Client{
+		Transport: &contextTransport{
+			transport: &MirrorTransport{
+				Rules: []*MirrorRule{
+					{Prefix: "https://files.example.com/", Mirrors: []string{server.URL + "/mirror/"}},
+				},
+			},
+		},
+	}
+	bundle := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("bundled " + req.URL.String())),
+			Request:    req,
+		}, nil
+	})
+
+	get := func(ctx context.Context, u string) string {
+		req, err := NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		contents, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(contents)
+	}
+
+	if body := get(context.Background(), "https://files.example.com/example.jar"); body != "/mirror/example.jar" {
+		t.Errorf("expected the mirror to serve the file, got %q", body)
+	}
+
+	// The context's transport is given the original URL, ahead of any mirrors
+	ctx := WithTransport(context.Background(), bundle)
+	if body := get(ctx, "https://files.example.com/example.jar"); body != "bundled https://files.example.com/example.jar" {
+		t.Errorf("expected the context's transport to serve the file, got %q", body)
+	}
+}
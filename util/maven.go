@@ -0,0 +1,35 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidArtifact = errors.New("util: invalid maven artifact")
+)
+
+// MavenPath gets the path of the given Maven artifact, relative to the
+// root of the repository. Artifacts take the form
+// group:artifact:version[:classifier][@extension].
+func MavenPath(name string) (string, error) {
+	extension := "jar"
+	if j := strings.LastIndex(name, "@"); j != -1 {
+		extension = name[j+1:]
+		name = name[:j]
+	}
+
+	parts := strings.Split(name, ":")
+	if len(parts) < 3 || len(parts) > 4 {
+		return "", ErrInvalidArtifact
+	}
+	file := parts[1] + "-" + parts[2]
+	if len(parts) == 4 {
+		file += "-" + parts[3]
+	}
+	return strings.Replace(parts[0], ".", "/", -1) + "/" + parts[1] + "/" + parts[2] + "/" + file + "." + extension, nil
+}
@@ -0,0 +1,27 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import "testing"
+
+func TestMavenPath(t *testing.T) {
+	for name, expected := range map[string]string{
+		"net.minecraftforge:forge:1.12.2-14.23.5.2860":           "net/minecraftforge/forge/1.12.2-14.23.5.2860/forge-1.12.2-14.23.5.2860.jar",
+		"net.minecraftforge:forge:1.16.5-36.2.39:universal":      "net/minecraftforge/forge/1.16.5-36.2.39/forge-1.16.5-36.2.39-universal.jar",
+		"de.oceanlabs.mcp:mcp_config:1.16.5-20210115.111550@zip": "de/oceanlabs/mcp/mcp_config/1.16.5-20210115.111550/mcp_config-1.16.5-20210115.111550.zip",
+	} {
+		path, err := MavenPath(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != expected {
+			t.Errorf("%s should be at %s, got %s", name, expected, path)
+		}
+	}
+
+	if _, err := MavenPath("net.minecraftforge"); err != ErrInvalidArtifact {
+		t.Errorf("expected ErrInvalidArtifact, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"encoding/binary"
+	"syscall"
+)
+
+// TotalMemory gets the total physical memory of this machine, in bytes.
+func TotalMemory() (uint64, error) {
+	value, err := syscall.Sysctl("hw.memsize")
+	if err != nil {
+		return 0, err
+	}
+
+	// Sysctl trims the trailing zero byte, which may be a part of the
+	// (little endian) value
+	b := []byte(value)
+	for len(b) < 8 {
+		b = append(b, 0)
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
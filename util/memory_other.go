@@ -0,0 +1,17 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package util
+
+import (
+	"errors"
+)
+
+// The total memory is only known on Linux, macOS and Windows, for now.
+func TotalMemory() (uint64, error) {
+	return 0, errors.New("util: the total memory isn't known on this platform")
+}
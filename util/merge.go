@@ -0,0 +1,139 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"errors"
+)
+
+const (
+	// The largest number of line comparisons a merge will make, to keep
+	// memory usage sensible.
+	maxMergeComparisons = 1 << 24
+)
+
+var (
+	ErrMergeTooLarge = errors.New("util: files are too large to merge")
+)
+
+// MergeLines performs a three-way merge of the given lines, where ours and
+// theirs have both been derived from base.
+// Changes made by only one side are applied automatically, while
+// overlapping changes are written as conflicts, using the given labels:
+//
+//	<<<<<<< ours
+//	...
+//	=======
+//	...
+//	>>>>>>> theirs
+//
+// The number of conflicts is returned alongside the merged lines.
+func MergeLines(base, ours, theirs []string, oursLabel, theirsLabel string) ([]string, int, error) {
+	oursMatches, err := matchLines(base, ours)
+	if err != nil {
+		return nil, 0, err
+	}
+	theirsMatches, err := matchLines(base, theirs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var merged []string
+	conflicts := 0
+
+	i, a, b := 0, 0, 0
+	for {
+		// Lines unchanged by both sides are kept
+		if i < len(base) && oursMatches[i] == a && theirsMatches[i] == b {
+			merged = append(merged, base[i])
+			i, a, b = i+1, a+1, b+1
+			continue
+		}
+
+		// Find the next line unchanged by both sides, everything up until
+		// then has been changed by at least one of them
+		j, nextA, nextB := len(base), len(ours), len(theirs)
+		for k := i; k < len(base); k++ {
+			if oursMatches[k] != -1 && theirsMatches[k] != -1 {
+				j, nextA, nextB = k, oursMatches[k], theirsMatches[k]
+				break
+			}
+		}
+
+		baseChunk, oursChunk, theirsChunk := base[i:j], ours[a:nextA], theirs[b:nextB]
+		if equalLines(oursChunk, baseChunk) {
+			merged = append(merged, theirsChunk...)
+		} else if equalLines(theirsChunk, baseChunk) || equalLines(oursChunk, theirsChunk) {
+			merged = append(merged, oursChunk...)
+		} else {
+			conflicts++
+			merged = append(merged, "<<<<<<< "+oursLabel)
+			merged = append(merged, oursChunk...)
+			merged = append(merged, "=======")
+			merged = append(merged, theirsChunk...)
+			merged = append(merged, ">>>>>>> "+theirsLabel)
+		}
+
+		i, a, b = j, nextA, nextB
+		if i == len(base) && a == len(ours) && b == len(theirs) {
+			break
+		}
+	}
+
+	return merged, conflicts, nil
+}
+
+// Matches the lines of base with those of other, using their longest common
+// subsequence. For each line of base, the index of the matching line in
+// other is given - or -1 should it not have been matched.
+func matchLines(base, other []string) ([]int, error) {
+	if (len(base)+1)*(len(other)+1) > maxMergeComparisons {
+		return nil, ErrMergeTooLarge
+	}
+
+	// lengths[x][y] is the length of the LCS of base[x:] and other[y:]
+	lengths := make([][]int32, len(base)+1)
+	for x := range lengths {
+		lengths[x] = make([]int32, len(other)+1)
+	}
+	for x := len(base) - 1; x >= 0; x-- {
+		for y := len(other) - 1; y >= 0; y-- {
+			if base[x] == other[y] {
+				lengths[x][y] = lengths[x+1][y+1] + 1
+			} else if lengths[x+1][y] >= lengths[x][y+1] {
+				lengths[x][y] = lengths[x+1][y]
+			} else {
+				lengths[x][y] = lengths[x][y+1]
+			}
+		}
+	}
+
+	matches := make([]int, len(base))
+	x, y := 0, 0
+	for x < len(base) {
+		if y < len(other) && base[x] == other[y] {
+			matches[x] = y
+			x, y = x+1, y+1
+		} else if y < len(other) && lengths[x][y+1] > lengths[x+1][y] {
+			y++
+		} else {
+			matches[x] = -1
+			x++
+		}
+	}
+	return matches, nil
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeLines(t *testing.T) {
+	base := []string{"a=1", "b=2", "c=3", "d=4"}
+
+	// Changes to different lines merge cleanly
+	{
+		ours := []string{"a=10", "b=2", "c=3", "d=4"}
+		theirs := []string{"a=1", "b=2", "c=3", "d=4", "e=5"}
+
+		merged, conflicts, err := MergeLines(base, ours, theirs, "ours", "theirs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conflicts != 0 {
+			t.Errorf("merge has %d conflicts, should have none", conflicts)
+		}
+		expected := "a=10\nb=2\nc=3\nd=4\ne=5"
+		if strings.Join(merged, "\n") != expected {
+			t.Errorf("merged is '%s', should be '%s'", strings.Join(merged, "\n"), expected)
+		}
+	}
+
+	// Changes to the same line conflict
+	{
+		ours := []string{"a=1", "b=20", "c=3", "d=4"}
+		theirs := []string{"a=1", "b=200", "c=3", "d=4"}
+
+		merged, conflicts, err := MergeLines(base, ours, theirs, "ours", "theirs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conflicts != 1 {
+			t.Errorf("merge has %d conflicts, should have 1", conflicts)
+		}
+		expected := "a=1\n<<<<<<< ours\nb=20\n=======\nb=200\n>>>>>>> theirs\nc=3\nd=4"
+		if strings.Join(merged, "\n") != expected {
+			t.Errorf("merged is '%s', should be '%s'", strings.Join(merged, "\n"), expected)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// MirrorRule rewrites requests for URLs beginning with Prefix, so they're
+// made to a mirror instead. Should a mirror fail, the next is tried.
+type MirrorRule struct {
+	Prefix string
+
+	// The prefixes to replace Prefix with, in order of preference. To fall
+	// back to the original host, include Prefix itself.
+	Mirrors []string
+}
+
+// Rewrites the given URL, for each of the rule's mirrors.
+func (r *MirrorRule) rewrite(u string) []string {
+	var urls []string
+	for _, mirror := range r.Mirrors {
+		urls = append(urls, mirror+strings.TrimPrefix(u, r.Prefix))
+	}
+	return urls
+}
+
+// MirrorTransport is an http.RoundTripper that makes requests by way of
+// mirrors, according to its rules. Should more than one rule match a
+// request, the one with the longest prefix is used.
+//
+// A mirror is considered to have failed should the request error, or it
+// respond with a 404 or server error - in which case the next mirror is
+// tried. The response of the last mirror is always returned.
+type MirrorTransport struct {
+	Rules []*MirrorRule
+
+	// The transport to make requests with, http.DefaultTransport if nil
+	Transport http.RoundTripper
+}
+
+// RoundTrip makes the request, by way of any mirrors.
+func (t *MirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	rule := t.match(req.URL.String())
+	if rule == nil || len(rule.Mirrors) == 0 {
+		return transport.RoundTrip(req)
+	}
+
+	// Requests with a body can only be made once, unless we can get the
+	// body again
+	urls := rule.rewrite(req.URL.String())
+	if req.Body != nil && req.GetBody == nil {
+		urls = urls[:1]
+	}
+
+	var resp *http.Response
+	var err error
+	for j, u := range urls {
+		mirrorReq, reqErr := mirrorRequest(req, u)
+		if reqErr != nil {
+			return nil, reqErr
+		}
+
+		resp, err = transport.RoundTrip(mirrorReq)
+		if j == len(urls)-1 || req.Context().Err() != nil || !mirrorFailed(resp, err) {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
+
+// Gets the rule with the longest prefix matching the given URL.
+func (t *MirrorTransport) match(u string) *MirrorRule {
+	var match *MirrorRule
+	for _, rule := range t.Rules {
+		if strings.HasPrefix(u, rule.Prefix) && (match == nil || len(rule.Prefix) > len(match.Prefix)) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// Creates a copy of the request, to the given URL.
+func mirrorRequest(req *http.Request, u string) (*http.Request, error) {
+	mirrorURL, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+
+	mirrorReq := req.Clone(req.Context())
+	mirrorReq.URL = mirrorURL
+	mirrorReq.Host = ""
+	if req.Body != nil && req.GetBody != nil {
+		mirrorReq.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mirrorReq, nil
+}
+
+// Determines whether a mirror failed to serve a request.
+func mirrorFailed(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode == http.StatusNotFound || resp.StatusCode >= 500
+}
+
+// ParseMirrorRules parses mirror rules, one per line, taking the form of
+// the prefix to rewrite followed by its mirrors - separated by
+// whitespace. Blank lines, and those starting with #, are ignored.
+//
+//	# Send CurseForge's CDN to our cache, falling back to the CDN itself
+//	https://media.forgecdn.net/ https://cache.lan/forgecdn/ https://media.forgecdn.net/
+func ParseMirrorRules(r io.Reader) ([]*MirrorRule, error) {
+	var rules []*MirrorRule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("util: line %d: expected a prefix followed by its mirrors", line)
+		}
+		rules = append(rules, &MirrorRule{
+			Prefix:  fields[0],
+			Mirrors: fields[1:],
+		})
+	}
+	return rules, scanner.Err()
+}
+
+// ReadMirrorRules reads the mirror rules in the given file, as with
+// ParseMirrorRules.
+func ReadMirrorRules(path string) ([]*MirrorRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseMirrorRules(f)
+}
+
+// UseMirrors makes all of mcinstall's requests by way of the given mirror
+// rules. Requests served by the transport of their context (see
+// WithTransport) aren't mirrored.
+func UseMirrors(rules []*MirrorRule) {
+	if t, ok := HTTPClient.Transport.(*contextTransport); ok {
+		t.transport = &MirrorTransport{
+			Rules:     rules,
+			Transport: t.transport,
+		}
+		return
+	}
+	HTTPClient.Transport = &MirrorTransport{
+		Rules:     rules,
+		Transport: HTTPClient.Transport,
+	}
+}
@@ -0,0 +1,87 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseMirrorRules(t *testing.T) {
+	rules, err := ParseMirrorRules(strings.NewReader(`
+# Send CurseForge's CDN to our cache
+https://media.forgecdn.net/ https://cache.lan/forgecdn/   https://media.forgecdn.net/
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Prefix != "https://media.forgecdn.net/" || len(rules[0].Mirrors) != 2 {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+
+	if _, err := ParseMirrorRules(strings.NewReader("https://media.forgecdn.net/")); err == nil {
+		t.Error("a rule without mirrors should be rejected")
+	}
+}
+
+func TestMirrorTransport(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/broken/"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case strings.HasPrefix(r.URL.Path, "/empty/"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			fmt.Fprint(w, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &MirrorTransport{
+			Rules: []*MirrorRule{
+				{Prefix: "https://files.example.com/", Mirrors: []string{server.URL + "/broken/", server.URL + "/empty/", server.URL + "/mirror/"}},
+				{Prefix: "https://files.example.com/maven/", Mirrors: []string{server.URL + "/maven/"}},
+			},
+		},
+	}
+
+	get := func(u string) string {
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		contents, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(contents)
+	}
+
+	// Falls back through the mirrors, in order
+	if body := get("https://files.example.com/mods/example.jar"); body != "/mirror/mods/example.jar" {
+		t.Errorf("expected the last mirror to serve the file, got %q", body)
+	}
+	if len(requests) != 3 || requests[0] != "/broken/mods/example.jar" || requests[1] != "/empty/mods/example.jar" {
+		t.Errorf("expected each mirror to be tried in order, got %v", requests)
+	}
+
+	// The longest prefix wins
+	if body := get("https://files.example.com/maven/forge.jar"); body != "/maven/forge.jar" {
+		t.Errorf("expected the maven mirror to serve the file, got %q", body)
+	}
+
+	// Other hosts are untouched
+	if body := get(server.URL + "/other"); body != "/other" {
+		t.Errorf("expected the request to be made as is, got %q", body)
+	}
+}
@@ -0,0 +1,17 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build !linux
+// +build !linux
+
+package util
+
+import (
+	"errors"
+)
+
+// Reflinks are only supported on Linux, for now.
+func reflink(src string, dst string) error {
+	return errors.New("util: reflinks aren't supported on this platform")
+}